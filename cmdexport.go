@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/notdodo/pastego/decoder"
+	"github.com/notdodo/pastego/export"
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/findings"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Export command
+var (
+	exportCmd     = kingpin.Command("export", "Export the saved bins to JSONL, CSV or to a Markdown or HTML report grouped by severity and rule")
+	exportFile    = exportCmd.Arg("file", "File of the export, stdout if missing").String()
+	exportFormat  = exportCmd.Flag("format", "Format of the export: "+strings.Join(export.Formats, ", ")).Short('f').Default("jsonl").Enum(export.Formats...)
+	exportRule    = exportCmd.Flag("rule", "Only the bins matching this rule").String()
+	exportSince   = exportCmd.Flag("since", "Only the bins fetched from this date: YYYY-MM-DD or RFC3339").String()
+	exportUntil   = exportCmd.Flag("until", "Only the bins fetched until this date, included: YYYY-MM-DD or RFC3339").String()
+	exportContext = exportCmd.Flag("context", "Bytes of content around the terms in the snippets").Default("40").Int()
+)
+
+// Parse a date of the export, the end of the day for 'until' dates without time
+func exportDate(s string, until bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q: use YYYY-MM-DD or RFC3339", s)
+	}
+	if until {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// Matching rules of a saved bin with the snippets of their terms: only the rule of the filter, if any
+func exportMatches(meta *filesupport.PasteMeta, text string, rule string, context int) []export.Match {
+	matches := meta.Matches
+	// Bins imported from the previous versions: only the name of the rule
+	if len(matches) == 0 && meta.Match != "" {
+		matches = []filesupport.RuleMatch{{Rule: meta.Match, Severity: meta.Severity}}
+	}
+	var layers []decoder.Layer
+	var out []export.Match
+	for _, m := range matches {
+		if rule != "" && m.Rule != rule {
+			continue
+		}
+		// Text of the spans: the title, the content or one of its decoded blobs, normalized by the rule
+		searched, where := text, m.Layer
+		if m.Title {
+			searched, where = meta.Title, "title"
+		} else if m.Layer != "" {
+			if layers == nil {
+				layers = decoder.Decode(text, matcher.DecodeDepth, matcher.DecodeMaxSize)
+			}
+			searched = ""
+			for _, l := range layers {
+				if l.Path == m.Layer {
+					searched = l.Text
+					break
+				}
+			}
+		}
+		if m.Normalized && matcher.Normalizer != nil {
+			searched = matcher.Normalizer.Normalize(searched)
+		}
+		out = append(out, export.Match{
+			Rule:     m.Rule,
+			Severity: m.Severity,
+			Where:    where,
+			Snippets: export.Snippets(searched, m.Spans, context),
+		})
+	}
+	return out
+}
+
+// Export the saved bins, returns the exit code
+func exportBins(file string, format string) int {
+	q := findings.Query{Rule: *exportRule}
+	var filters []string
+	var err error
+	if *exportRule != "" {
+		filters = append(filters, "rule "+*exportRule)
+	}
+	if *exportSince != "" {
+		if q.Since, err = exportDate(*exportSince, false); err != nil {
+			kingpin.Fatalf("--since: %s", err)
+		}
+		filters = append(filters, "since "+*exportSince)
+	}
+	if *exportUntil != "" {
+		if q.Until, err = exportDate(*exportUntil, true); err != nil {
+			kingpin.Fatalf("--until: %s", err)
+		}
+		filters = append(filters, "until "+*exportUntil)
+	}
+
+	findingsDB = openFindingsReadOnly()
+	defer findingsDB.Close()
+	list, err := findingsDB.Query(q)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	r := &export.Report{Generated: time.Now(), Filters: strings.Join(filters, ", ")}
+	for _, meta := range list {
+		text, err := filesupport.ReadBin(meta, store)
+		if err != nil {
+			// Export the metadata anyway
+			fmt.Fprintf(os.Stderr, "%s: %s\n", meta.Name, err)
+		}
+		r.Findings = append(r.Findings, &export.Finding{PasteMeta: meta, Rules: exportMatches(meta, text, *exportRule, *exportContext)})
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, r); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if file == "" || file == "-" {
+		os.Stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := filesupport.WriteFile(file, buf.Bytes(), filesupport.FileMode); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d bins to %s\n", len(r.Findings), file)
+	return 0
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/findings"
+	"github.com/notdodo/pastego/gui"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Import command
+var (
+	importCmd = kingpin.Command("import", "Import a folder of saved bins, even of the previous versions, in the output folder and in the findings database")
+	importDir = importCmd.Arg("folder", "Folder of the saved bins").Required().ExistingDir()
+)
+
+// Open the findings database of the output folder
+func openFindings() *findings.DB {
+	dir := filepath.Join(*outputTo, filesupport.MetaDir)
+	if err := os.MkdirAll(dir, filesupport.DirMode); err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+	db, err := findings.Open(filepath.Join(dir, findings.FileName))
+	if err != nil {
+		kingpin.Fatalf("%s: %s", filepath.Join(dir, findings.FileName), err)
+	}
+	db.IndexKey = filesupport.Key
+	return db
+}
+
+// Open the findings database for the commands only reading it: they run next to the scraper and the TUI
+func openFindingsReadOnly() *findings.DB {
+	path := filepath.Join(*outputTo, filesupport.MetaDir, findings.FileName)
+	db, err := findings.OpenReadOnly(path)
+	if os.IsNotExist(err) {
+		kingpin.Fatalf("%s: no findings database, run pastego on the output folder first", path)
+	} else if err != nil {
+		kingpin.Fatalf("%s: %s", path, err)
+	}
+	db.IndexKey = filesupport.Key
+	return db
+}
+
+// Import the bins of the output folder on the first run with the database
+func importSaved() {
+	if findingsDB.Count() > 0 {
+		return
+	}
+	if n, err := importBins(store); err != nil {
+		logger.Error("Import of the saved bins failed", "err", err)
+	} else if n > 0 {
+		logger.Info("Imported the saved bins in the database", "bins", n)
+	}
+}
+
+// IDs of the store that are not bins, i.e. the metadata of the plain files: not read again by syncSaved
+var syncSkipped = map[string]bool{}
+
+// Import in the database the bins of the store missing from it, i.e. saved by the other hosts sharing
+// the bucket: returns the number of bins imported
+func syncSaved() int {
+	ids, err := filesupport.ListIDs(store)
+	if err != nil {
+		logger.Warn("Saved bins not synchronized", "err", err)
+		return 0
+	}
+	var unknown []string
+	for _, id := range ids {
+		if !syncSkipped[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	missing, err := findingsDB.Missing(unknown)
+	if err != nil {
+		logger.Warn("Saved bins not synchronized", "err", err)
+		return 0
+	}
+	n := 0
+	for _, id := range missing {
+		meta, err := filesupport.ReadMeta(id, store)
+		if err != nil {
+			logger.Warn("Saved bin not synchronized", "id", id, "err", err)
+			continue
+		}
+		if meta.Hash == "" || meta.ID != id {
+			syncSkipped[id] = true
+			continue
+		}
+		text, err := filesupport.ReadBin(meta, store)
+		if err != nil {
+			logger.Warn("Saved bin not synchronized", "id", id, "err", err)
+			continue
+		}
+		if err := indexBin(meta, matcher.Layers(text)); err != nil {
+			logger.Warn("Saved bins not synchronized", "err", err)
+			break
+		}
+		n++
+	}
+	if n > 0 {
+		logger.Info("Synchronized the bins saved by the other hosts", "bins", n)
+		gui.ListDir()
+	}
+	return n
+}
+
+// Import the bins of a store in the store of the output and in the database: the bins saved as plain files
+// in the store of the output are moved in the storage. Returns the number of bins imported
+func importBins(src filesupport.Store) (int, error) {
+	list, err := filesupport.List(src)
+	if err != nil {
+		return 0, err
+	}
+	same := src.String() == store.String()
+	n := 0
+	for _, meta := range list {
+		// Already in the storage of the output folder: only the database is missing
+		text, err := filesupport.ReadBin(meta, src)
+		if err != nil {
+			return n, err
+		}
+		if meta.Hash != "" && same {
+			if err := indexBin(meta, matcher.Layers(text)); err != nil {
+				return n, err
+			}
+			n++
+			continue
+		}
+		bin := *meta
+		legacy := meta.Hash == ""
+		if legacy {
+			// Plain file named 'match__title'
+			if i := strings.Index(meta.Name, "__"); i > 0 && bin.Match == "" {
+				bin.Match, bin.Title = meta.Name[:i], meta.Name[i+2:]
+			}
+			if bin.Key == "" {
+				bin.Key = meta.Name
+			}
+			if bin.Source == "" {
+				bin.Source = "import"
+			}
+			if local, ok := src.(*filesupport.Local); ok && bin.FetchedAt.IsZero() {
+				if info, err := os.Stat(filepath.Join(local.Dir, meta.Name)); err == nil {
+					bin.FetchedAt = info.ModTime()
+				}
+			}
+		}
+		saved, err := filesupport.Save(&bin, text, store)
+		if err != nil {
+			return n, err
+		}
+		if saved {
+			if err := indexBin(&bin, matcher.Layers(text)); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if legacy && same {
+			if err := filesupport.DeleteFile(meta.Name, src); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Import a folder, returns the exit code
+func importFolder(dir string) int {
+	findingsDB = openFindings()
+	defer findingsDB.Close()
+	n, err := importBins(filesupport.NewLocal(dir))
+	fmt.Printf("Imported %d bins from %s\n", n, dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/findings"
+	"github.com/notdodo/pastego/gui"
+	"github.com/notdodo/pastego/logging"
+	"github.com/notdodo/pastego/retention"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Purge command
+var (
+	purgeCmd    = kingpin.Command("purge", "Delete the bins out of the retention policy: --max-age, --max-size and --severity-max-age")
+	purgeDryRun = purgeCmd.Flag("dry-run", "Only print the bins to delete").Bool()
+)
+
+// Retention policy from the command line, nil without limits
+var retentionPolicy *retention.Policy
+
+// Load the retention policy
+func loadRetention() *retention.Policy {
+	p := &retention.Policy{
+		MaxSize:           int64(*maxSize),
+		SeverityMaxAge:    map[string]time.Duration{},
+		KeepTruePositives: *keepTrue,
+	}
+	var err error
+	if *maxAge != "" {
+		if p.MaxAge, err = retention.ParseAge(*maxAge); err != nil {
+			kingpin.Fatalf("--max-age: %s", err)
+		}
+	}
+	for severity, age := range *severityAge {
+		if p.SeverityMaxAge[severity], err = retention.ParseAge(age); err != nil {
+			kingpin.Fatalf("--severity-max-age: %s", err)
+		}
+	}
+	if !p.Enabled() {
+		return nil
+	}
+	return p
+}
+
+// Delete the bins out of the retention policy from the output folder and the database, logging them.
+// Returns the bins deleted, or to delete with dryRun
+func purgeBins(dryRun bool) ([]retention.Bin, error) {
+	if retentionPolicy == nil || findingsDB == nil {
+		return nil, nil
+	}
+	list, err := findingsDB.Query(findings.Query{})
+	if err != nil {
+		return nil, err
+	}
+	bins := make([]retention.Bin, 0, len(list))
+	for _, meta := range list {
+		b := retention.Bin{Meta: meta}
+		// A request per bin on a bucket: only with a size limit
+		if retentionPolicy.MaxSize > 0 {
+			if b.Size, err = filesupport.Size(meta, store); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		bins = append(bins, b)
+	}
+	purged := retentionPolicy.Purge(bins, time.Now())
+	if dryRun {
+		return purged, nil
+	}
+	for i, b := range purged {
+		if err := filesupport.Delete(b.Meta, store); err != nil {
+			return purged[:i], err
+		}
+		if err := findingsDB.Delete(b.Meta.ID); err != nil {
+			return purged[:i], err
+		}
+		logger.Info("Purged", "name", b.Meta.Name, "id", b.Meta.ID, "reason", b.Reason)
+	}
+	return purged, nil
+}
+
+// Enforce the retention policy between the cycles of run()
+func enforceRetention() {
+	purged, err := purgeBins(false)
+	if err != nil {
+		report(logging.Error, "Retention: "+err.Error())
+	}
+	if len(purged) > 0 {
+		report(logging.Info, fmt.Sprintf("Retention: purged %d bins", len(purged)))
+		gui.ListDir()
+	}
+}
+
+// Purge the output folder, returns the exit code
+func purge(dryRun bool) int {
+	if retentionPolicy == nil {
+		fmt.Fprintln(os.Stderr, "no retention policy, use --max-age, --max-size or --severity-max-age")
+		return 2
+	}
+	findingsDB = openFindings()
+	defer findingsDB.Close()
+	importSaved()
+	syncSaved()
+	purged, err := purgeBins(dryRun)
+	for _, b := range purged {
+		fmt.Printf("%s\t%s\t%s\n", b.Meta.FetchedAt.Format(time.RFC3339), b.Meta.Name, b.Reason)
+	}
+	if dryRun {
+		fmt.Printf("%d bins to purge\n", len(purged))
+	} else {
+		fmt.Printf("Purged %d bins\n", len(purged))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
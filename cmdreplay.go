@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/logging"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Replay command
+var (
+	replayCmd    = kingpin.Command("replay", "Run the bins of an archive through the rules, saving the matches in the output folder")
+	replaySource = replayCmd.Arg("source", "JSONL file, a bin per line with the pastebin fields and its 'body', or a folder of saved bins").Required().ExistingFileOrDir()
+	replaySpeed  = replayCmd.Flag("speed", "Replay the bins respecting their dates, accelerated by this factor (i.e. 60: an hour per minute). 0 to replay without delays").Default("0").Float64()
+)
+
+// Bin of a JSONL archive
+type archivedBin struct {
+	filesupport.PasteJSON
+	Body string `json:"body"`
+}
+
+// Replay the bins of a JSONL file or of a folder, returns the exit code
+func replay(path string, speed float64) int {
+	headless = true
+	fmt.Fprintln(os.Stderr, "Log file:", logger.Path)
+	findingsDB = openFindings()
+	defer findingsDB.Close()
+	var last time.Time
+	bins, saved := 0, 0
+	source := "replay:" + path
+	each := func(link *filesupport.PasteJSON, text string) {
+		if date := binDate(link); speed > 0 && !date.IsZero() {
+			if !last.IsZero() && date.After(last) {
+				time.Sleep(time.Duration(float64(date.Sub(last)) / speed))
+			}
+			last = date
+		}
+		bins++
+		if processBin(link, text, source) {
+			saved++
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		err = replayDir(path, each)
+	} else if err == nil {
+		err = replayJSONL(path, each)
+	}
+	saved += savePending()
+	indexPending()
+	saveSuppressStats()
+	report(logging.Info, fmt.Sprintf("Replayed %d bins from %s: %d saved", bins, path, saved))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(os.Stderr, "%d bins not saved: storage unavailable\n", len(pending))
+		return 2
+	}
+	if len(unindexed) > 0 {
+		fmt.Fprintf(os.Stderr, "%d bins not indexed: findings database unavailable\n", len(unindexed))
+		return 2
+	}
+	return 0
+}
+
+// Date of a bin: pastebin dates are unix timestamps
+func binDate(link *filesupport.PasteJSON) time.Time {
+	sec, err := strconv.ParseInt(link.Date, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// Read the bins of a JSONL file
+func replayJSONL(path string, each func(*filesupport.PasteJSON, string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var bin archivedBin
+			if err := json.Unmarshal(line, &bin); err != nil {
+				return fmt.Errorf("%s:%d: %s", path, n, err)
+			}
+			each(&bin.PasteJSON, bin.Body)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Read the saved bins of a folder, with their metadata if any: the hidden folders are skipped
+func replayDir(dir string, each func(*filesupport.PasteJSON, string)) error {
+	// Bins of the content-addressed storage of an output folder
+	saved, _ := filesupport.List(filesupport.NewLocal(dir))
+	for _, meta := range saved {
+		if meta.Hash == "" {
+			continue
+		}
+		text, err := filesupport.ReadBin(meta, filesupport.NewLocal(dir))
+		if err != nil {
+			return err
+		}
+		each(&meta.PasteJSON, text)
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		link := &filesupport.PasteJSON{Key: info.Name(), Date: strconv.FormatInt(info.ModTime().Unix(), 10)}
+		if meta, err := filesupport.ReadMeta(info.Name(), filesupport.NewLocal(filepath.Dir(path))); err == nil {
+			link = &meta.PasteJSON
+		}
+		each(link, string(b))
+		return nil
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/notdodo/pastego/pegmatch"
+	"github.com/notdodo/pastego/rules"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Rules commands
+var (
+	runCmd           = kingpin.Command("run", "Scrape pastebin and show the findings").Default()
+	rulesCmd         = kingpin.Command("rules", "Manage the rules")
+	rulesTestCmd     = rulesCmd.Command("test", "Run the rules against the labelled samples: '<samples>/<rule>/match/*' and '<samples>/<rule>/nomatch/*'")
+	rulesTestSamples = rulesTestCmd.Arg("samples", "Folder of the samples").Required().ExistingDir()
+	rulesExplainCmd  = rulesCmd.Command("explain", "Show the truth value of each node of the expression of a rule and the position of the hits in a bin")
+	rulesExplainRule = rulesExplainCmd.Flag("rule", "Name of the rule").Required().String()
+	rulesExplainFile = rulesExplainCmd.Flag("file", "Bin to check").Required().ExistingFile()
+)
+
+// Run the rules against the samples and print the regressions, returns the exit code
+func testRules(samples string) int {
+	results, err := rules.RunTests(matcher, samples)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	code := 0
+	for _, r := range results {
+		switch {
+		case len(r.Failures) > 0:
+			code = 1
+			fmt.Printf("FAIL\t%s\t%d/%d samples failed\n", r.Rule, len(r.Failures), r.Samples)
+			for _, f := range r.Failures {
+				fmt.Printf("\t\t%s\n", f)
+			}
+		case r.Samples == 0:
+			fmt.Printf("?\t%s\t[no samples]\n", r.Rule)
+		default:
+			fmt.Printf("ok\t%s\t%d samples\n", r.Rule, r.Samples)
+		}
+	}
+	return code
+}
+
+// Print the expression tree of a rule evaluated against a bin, returns the exit code: 0 if the rule matches
+func explainRule(name string, file string) int {
+	var rule *rules.Rule
+	for _, r := range matcher.Rules {
+		if r.Name == name {
+			rule = r
+		}
+	}
+	if rule == nil {
+		fmt.Printf("unknown rule %q\n", name)
+		return 2
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	tree, layer, text, err := matcher.Explain(rule, string(content))
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	fmt.Printf("%s: %s\n", rule.Name, rule.Expr)
+	if layer != "" {
+		fmt.Printf("decoded: %s\n", layer)
+	}
+	printNode(tree, text, 0)
+	if !tree.Value {
+		return 1
+	}
+	return 0
+}
+
+// Print a node and its children, the hits as line:column of the searched text
+func printNode(n *pegmatch.Node, text string, depth int) {
+	label := n.Text
+	if n.Op != "" {
+		label = n.Op
+	}
+	var hits []string
+	for _, h := range n.Hits {
+		hits = append(hits, position(text, h.Start))
+	}
+	fmt.Printf("%-5t  %s%s", n.Value, strings.Repeat("  ", depth), label)
+	if len(hits) > 0 {
+		fmt.Printf("  at %s", strings.Join(hits, ", "))
+	}
+	fmt.Println()
+	for _, c := range n.Children {
+		printNode(c, text, depth+1)
+	}
+}
+
+// Position of a byte offset of the text as line:column
+func position(text string, off int) string {
+	line := strings.Count(text[:off], "\n") + 1
+	col := off - strings.LastIndex(text[:off], "\n")
+	return fmt.Sprintf("%d:%d", line, col)
+}
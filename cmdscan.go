@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/notdodo/pastego/rules"
+	"github.com/notdodo/pastego/unpack"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Scan command
+var (
+	scanCmd   = kingpin.Command("scan", "Search the rules in local files and folders, or stdin, like grep: exit code 0 if something matches, 1 if not, 2 on errors")
+	scanPaths = scanCmd.Arg("paths", "Files or folders (recursive) to scan, none or '-- -' to read stdin").Strings()
+	// Limits of the archives
+	scanArchiveDepth = scanCmd.Flag("archive-depth", "Scan the members of zip/tar/gzip archives nested up to this depth, 0 to scan the archives as they are").Default("3").Int()
+	scanMemberSize   = scanCmd.Flag("archive-max-size", "Maximum uncompressed size of a member of an archive").Default("104857600").Int64()
+	scanArchiveSize  = scanCmd.Flag("archive-max-total", "Maximum uncompressed size of all the members of an archive").Default("536870912").Int64()
+	scanArchiveRatio = scanCmd.Flag("archive-max-ratio", "Maximum compression ratio of a member of an archive").Default("200").Float64()
+)
+
+// Scan the paths and print the spans of the matching rules as 'file:line:column: rule [severity] text',
+// returns the exit code
+func scan(paths []string) int {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	matched, failed := false, false
+	for _, path := range paths {
+		if path == "-" {
+			content, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				continue
+			}
+			matched = scanFile("(standard input)", content) || matched
+			continue
+		}
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				return nil
+			}
+			matched = scanFile(file, content) || matched
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	switch {
+	case failed:
+		return 2
+	case matched:
+		return 0
+	}
+	return 1
+}
+
+// Scan a file, or the members of an archive, returns true if any rule matches
+func scanFile(name string, content []byte) bool {
+	if *scanArchiveDepth <= 0 {
+		return scanContent(name, string(content))
+	}
+	limits := unpack.Limits{
+		MaxSize:  *scanMemberSize,
+		MaxTotal: *scanArchiveSize,
+		MaxRatio: *scanArchiveRatio,
+		MaxDepth: *scanArchiveDepth,
+	}
+	matched := false
+	unpack.Walk(name, content, limits, func(m unpack.Member) {
+		if m.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: skipped: %s\n", m.Path, m.Err)
+			return
+		}
+		matched = scanContent(m.Path, string(m.Data)) || matched
+	})
+	return matched
+}
+
+// Print the rules matching a content, returns true if any matches
+func scanContent(name string, content string) bool {
+	hits := matcher.MatchAll(content)
+	for _, h := range hits {
+		printHit(name, content, h)
+	}
+	return len(hits) > 0
+}
+
+// Print a line for each span of the hit, or a single line if the rule has no spans (i.e. functions)
+func printHit(name string, content string, h rules.Hit) {
+	rule := fmt.Sprintf("%s [%s]", h.Rule.Name, h.Rule.Severity)
+	if h.Layer != "" {
+		rule += " (" + h.Layer + ")"
+	}
+	if len(h.Spans) == 0 {
+		fmt.Printf("%s: %s\n", name, rule)
+		return
+	}
+	// Show the original text when the offsets are the same: not decoded and not normalized
+	text := h.Text
+	if h.Layer == "" && !h.Rule.Normalize && len(text) == len(content) {
+		text = content
+	}
+	for _, s := range h.Spans {
+		fmt.Printf("%s:%s: %s %q\n", name, position(text, s.Start), rule, text[s.Start:s.End])
+	}
+}
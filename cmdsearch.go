@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/notdodo/pastego/decoder"
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/rules"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Search command
+var (
+	searchCmd   = kingpin.Command("search", "Search the saved bins with an expression, the most recent first: exit code 0 if something is found, 1 if not, 2 on errors")
+	searchQuery = searchCmd.Arg("query", "Expression, i.e: \"password && ~php\"").Required().String()
+	searchLimit = searchCmd.Flag("limit", "Maximum number of results, 0 for all").Default("50").Int()
+)
+
+// Text of a bin for the search index: the content and the blobs decoded from it
+func searchableText(layers []decoder.Layer) string {
+	var texts []string
+	for _, l := range layers {
+		texts = append(texts, l.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Save a bin in the findings database and in the search index, the layers are decoded by Matcher.Layers
+func indexBin(meta *filesupport.PasteMeta, layers []decoder.Layer) error {
+	if err := findingsDB.Put(meta); err != nil {
+		return err
+	}
+	return findingsDB.Index(meta, searchableText(layers))
+}
+
+// Search the saved bins matching an expression, the most recent first
+func searchBins(query string, limit int) ([]*filesupport.PasteMeta, error) {
+	expr, err := macros.Expand(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := rules.Compile(expr); err != nil {
+		return nil, err
+	}
+	rule := &rules.Rule{Name: "search", Expr: expr}
+	return findingsDB.Search(expr, limit, func(meta *filesupport.PasteMeta) bool {
+		text, err := filesupport.ReadBin(meta, store)
+		if err != nil {
+			return false
+		}
+		ok, _ := matcher.MatchRule(rule, text)
+		return ok
+	})
+}
+
+// Print the saved bins matching an expression, returns the exit code
+func search(query string, limit int) int {
+	findingsDB = openFindingsReadOnly()
+	defer findingsDB.Close()
+	found, err := searchBins(query, limit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, meta := range found {
+		fmt.Printf("%s\t%s\t%s\t%s\n", meta.FetchedAt.Format("2006-01-02 15:04:05"), meta.Name, meta.FullURL, meta.Match)
+	}
+	if len(found) == 0 {
+		return 1
+	}
+	return 0
+}
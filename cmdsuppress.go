@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	// import third party libraries
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Suppressions commands
+var suppressCmd = kingpin.Command("suppressions", "Show how many bins every suppression silenced, to prune the stale ones")
+
+// Print the suppressions with their stats
+func listSuppressions() {
+	if suppressions == nil {
+		kingpin.Fatalf("no suppressions, use --suppress")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tHITS\tLAST HIT\tEXPIRES\t")
+	now := time.Now()
+	for _, e := range suppressions.Entries {
+		hits, last := 0, "never"
+		if s, ok := suppressions.Stats[e.Name]; ok {
+			hits, last = s.Hits, s.LastHit.Format(time.RFC3339)
+		}
+		expires := e.Expires
+		if e.Expired(now) {
+			expires += " (expired)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t\n", e.Name, hits, last, expires)
+	}
+	w.Flush()
+}
@@ -0,0 +1,88 @@
+package combolist
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Credential pairs found in a bin, emails are grouped by domain
+type Result struct {
+	Total    int            `json:"total"`
+	ByDomain map[string]int `json:"by_domain,omitempty"`
+}
+
+var (
+	// email:pass, email|pass, email;pass
+	reEmailPair = regexp.MustCompile(`^([A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)+))[:|;](\S{3,128})$`)
+	// user:pass
+	reUserPair = regexp.MustCompile(`^([A-Za-z0-9._\-]{3,64}):(\S{3,128})$`)
+	reLetter   = regexp.MustCompile(`[A-Za-z]`)
+
+	mutex sync.Mutex
+	// Result of the last bin: every rule of the same bin reuses it
+	lastText   string
+	lastResult *Result
+)
+
+// Parse counts the unique credential pairs, one per line
+func Parse(text string) *Result {
+	r := &Result{ByDomain: map[string]int{}}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if seen[line] {
+			continue
+		}
+		if m := reEmailPair.FindStringSubmatch(line); m != nil {
+			seen[line] = true
+			r.Total++
+			r.ByDomain[strings.ToLower(m[2])]++
+		} else if m := reUserPair.FindStringSubmatch(line); m != nil && reLetter.MatchString(m[1]) &&
+			!strings.HasPrefix(m[2], "//") && !strings.Contains(m[2], ":") {
+			seen[line] = true
+			r.Total++
+		}
+	}
+	return r
+}
+
+// Count returns the number of pairs for the domains (or their subdomains), or all of them:
+// a pair matching more domains is counted once
+func (r *Result) Count(domains ...string) int {
+	if len(domains) == 0 {
+		return r.Total
+	}
+	count := 0
+	for d, n := range r.ByDomain {
+		for _, domain := range domains {
+			domain = strings.ToLower(domain)
+			if d == domain || strings.HasSuffix(d, "."+domain) {
+				count += n
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Combos is the expression function 'combos()': 'combos(domain=corp.com) > 0'
+func Combos(arg string, content string) (int, error) {
+	var domains []string
+	for _, a := range strings.Fields(arg) {
+		if !strings.HasPrefix(a, "domain=") {
+			return 0, fmt.Errorf("combos: unknown argument %q", a)
+		}
+		domains = append(domains, strings.TrimPrefix(a, "domain="))
+	}
+
+	mutex.Lock()
+	if lastResult == nil || content != lastText {
+		lastText, lastResult = content, Parse(content)
+	}
+	r := lastResult
+	mutex.Unlock()
+
+	return r.Count(domains...), nil
+}
@@ -0,0 +1,36 @@
+package combolist_test
+
+import (
+	"testing"
+
+	"github.com/notdodo/pastego/combolist"
+)
+
+func TestParse(t *testing.T) {
+	text := `
+		john@corp.com:hunter22
+		jane@mail.corp.com|s3cr3t!
+		bob@other.org;qwerty
+		john@corp.com:hunter22
+		admin:toor123
+		http://example.com
+		12:30:45
+		Date: Monday`
+	r := combolist.Parse(text)
+	if r.Total != 4 {
+		t.Error("total", r.Total)
+	}
+	if r.Count("corp.com") != 2 || r.Count("other.org") != 1 || r.Count("mail.corp.com") != 1 {
+		t.Error("by domain", r.ByDomain)
+	}
+	if n, err := combolist.Combos("domain=corp.com domain=other.org", text); err != nil || n != 3 {
+		t.Error("combos", n, err)
+	}
+	// Overlapping domains count each pair once
+	if n, err := combolist.Combos("domain=corp.com domain=mail.corp.com", text); err != nil || n != 2 {
+		t.Error("overlapping domains", n, err)
+	}
+	if _, err := combolist.Combos("user=john", text); err == nil {
+		t.Error("unknown argument accepted")
+	}
+}
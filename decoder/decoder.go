@@ -0,0 +1,132 @@
+package decoder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Layer is the content of a bin or a blob decoded from it
+type Layer struct {
+	// Decoders applied to get the layer, i.e. 'base64>gzip'; empty for the original content
+	Path  string
+	Depth int
+	Text  string
+}
+
+var (
+	reBase64 = regexp.MustCompile(`[A-Za-z0-9+/\-_]{24,}={0,2}`)
+	reHex    = regexp.MustCompile(`(?:[0-9A-Fa-f]{2}){12,}`)
+	reURL    = regexp.MustCompile(`(?:[^\s%]*%[0-9A-Fa-f]{2}){3,}[^\s%]*`)
+)
+
+// Decode finds base64, hex and URL-encoded blobs (even gzip or zlib compressed)
+// in the text and decodes them up to 'maxDepth' times. The first layer is the text itself.
+// 'maxSize' limits the total amount of decoded bytes.
+func Decode(text string, maxDepth int, maxSize int) []Layer {
+	layers := []Layer{{Text: text}}
+	budget := maxSize
+	for i := 0; i < len(layers); i++ {
+		parent := layers[i]
+		if parent.Depth >= maxDepth || budget <= 0 {
+			continue
+		}
+		for _, blob := range blobs(parent.Text, budget) {
+			budget -= len(blob.Text)
+			if budget < 0 {
+				break
+			}
+			blob.Depth = parent.Depth + 1
+			if parent.Path != "" {
+				blob.Path = parent.Path + ">" + blob.Path
+			}
+			layers = append(layers, blob)
+		}
+	}
+	return layers
+}
+
+// Decode every blob found in the text: only printable results are kept
+func blobs(text string, maxSize int) []Layer {
+	var out []Layer
+	seen := map[string]bool{}
+	add := func(name string, b []byte) {
+		if kind, inflated := decompress(b, maxSize); kind != "" {
+			name, b = name+">"+kind, inflated
+		}
+		if s := string(b); printable(s) && !seen[s] && strings.TrimSpace(s) != "" {
+			seen[s] = true
+			out = append(out, Layer{Path: name, Text: s})
+		}
+	}
+
+	for _, m := range reBase64.FindAllString(text, -1) {
+		for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if b, err := enc.DecodeString(m); err == nil {
+				add("base64", b)
+				break
+			}
+		}
+	}
+	for _, m := range reHex.FindAllString(text, -1) {
+		if b, err := hex.DecodeString(m); err == nil {
+			add("hex", b)
+		}
+	}
+	for _, m := range reURL.FindAllString(text, -1) {
+		if s, err := url.QueryUnescape(m); err == nil {
+			add("url", []byte(s))
+		}
+	}
+	return out
+}
+
+// Inflate gzip or zlib data, returns the name of the compression
+func decompress(b []byte, maxSize int) (string, []byte) {
+	var r io.Reader
+	var kind string
+	var err error
+	switch {
+	case len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b:
+		kind = "gzip"
+		r, err = gzip.NewReader(bytes.NewReader(b))
+	case len(b) > 2 && b[0] == 0x78 && (b[1] == 0x01 || b[1] == 0x5e || b[1] == 0x9c || b[1] == 0xda):
+		kind = "zlib"
+		r, err = zlib.NewReader(bytes.NewReader(b))
+	default:
+		return "", nil
+	}
+	if err != nil {
+		return "", nil
+	}
+	// Avoid decompression bombs
+	out, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)))
+	if err != nil && len(out) == 0 {
+		return "", nil
+	}
+	return kind, out
+}
+
+// At least 90% of the runes must be printable
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	total, bad := 0, 0
+	for _, r := range s {
+		total++
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			bad++
+		}
+	}
+	return total > 0 && bad*10 <= total
+}
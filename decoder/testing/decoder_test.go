@@ -0,0 +1,51 @@
+package decoder_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/notdodo/pastego/decoder"
+)
+
+func find(layers []decoder.Layer, path string) *decoder.Layer {
+	for i := range layers {
+		if layers[i].Path == path {
+			return &layers[i]
+		}
+	}
+	return nil
+}
+
+func TestDecode(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("the password is hunter2"))
+	w.Close()
+	inner := base64.StdEncoding.EncodeToString(gz.Bytes())
+	outer := hex.EncodeToString([]byte("leak: " + inner))
+	text := "nothing here " + outer + " d41d8cd98f00b204e9800998ecf8427e"
+
+	layers := decoder.Decode(text, 2, 1<<20)
+	if layers[0].Path != "" || layers[0].Text != text {
+		t.Error("first layer is not the bin")
+	}
+	if l := find(layers, "hex"); l == nil || l.Depth != 1 {
+		t.Error("hex layer not found")
+	}
+	if l := find(layers, "hex>base64>gzip"); l == nil || l.Text != "the password is hunter2" || l.Depth != 2 {
+		t.Error("hex>base64>gzip layer not found", layers)
+	}
+	// Hashes are not printable once decoded
+	if len(layers) != 3 {
+		t.Error("unexpected layers", len(layers))
+	}
+	if layers := decoder.Decode(text, 1, 1<<20); len(layers) != 2 {
+		t.Error("depth not respected", len(layers))
+	}
+	if layers := decoder.Decode(text, 2, 0); len(layers) != 1 {
+		t.Error("size not respected", len(layers))
+	}
+}
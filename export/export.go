@@ -0,0 +1,219 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/indicators"
+	"github.com/notdodo/pastego/pegmatch"
+	"github.com/notdodo/pastego/rules"
+)
+
+// Formats of the export
+var Formats = []string{"jsonl", "csv", "markdown", "html"}
+
+// Maximum number of snippets of a rule
+const maxSnippets = 5
+
+// Snippet of text around the terms of a rule, the hits are relative to the snippet
+type Snippet struct {
+	Text string          `json:"text"`
+	Hits []pegmatch.Span `json:"hits,omitempty"`
+}
+
+// Matching rule of a bin with the snippets of its terms
+type Match struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity,omitempty"`
+	// Where the terms are: 'title', the decoders of a layer, i.e. 'base64>gzip', or empty for the content
+	Where    string    `json:"where,omitempty"`
+	Snippets []Snippet `json:"snippets,omitempty"`
+}
+
+// Finding is a saved bin with its metadata and the snippets of its matching rules
+type Finding struct {
+	*filesupport.PasteMeta
+	Rules []Match `json:"rules,omitempty"`
+}
+
+// Report of the findings, the most recent first
+type Report struct {
+	Generated time.Time
+	// Description of the filters of the findings, i.e. 'rule password since 2021-03-01'
+	Filters  string
+	Findings []*Finding
+}
+
+// Snippets of the text around the spans, 'context' bytes on both sides: overlapping snippets are merged
+func Snippets(text string, spans []pegmatch.Span, context int) []Snippet {
+	spans = append([]pegmatch.Span(nil), spans...)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	var out []Snippet
+	end := -1
+	for _, s := range spans {
+		if s.Start < 0 || s.End > len(text) || s.Start >= s.End {
+			continue
+		}
+		start, stop := runeStart(text, s.Start-context), runeStart(text, s.End+context)
+		if len(out) > 0 && start <= end {
+			// Merge with the previous snippet
+			last := &out[len(out)-1]
+			base := end - len(last.Text)
+			if stop > end {
+				last.Text += text[end:stop]
+				end = stop
+			}
+			last.Hits = append(last.Hits, pegmatch.Span{Start: s.Start - base, End: s.End - base})
+			continue
+		}
+		if len(out) == maxSnippets {
+			break
+		}
+		out = append(out, Snippet{Text: text[start:stop], Hits: []pegmatch.Span{{Start: s.Start - start, End: s.End - start}}})
+		end = stop
+	}
+	return out
+}
+
+// Offset moved inside the text and on the start of a rune
+func runeStart(text string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(text) {
+		return len(text)
+	}
+	for i > 0 && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
+
+// Write the report in a format
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case "jsonl":
+		return JSONL(w, r)
+	case "csv":
+		return CSV(w, r)
+	case "markdown":
+		return Markdown(w, r)
+	case "html":
+		return HTML(w, r)
+	}
+	return fmt.Errorf("unknown format %q: use %s", format, strings.Join(Formats, ", "))
+}
+
+// JSONL writes a finding per line: its metadata, rules, indicators and snippets
+func JSONL(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	for _, f := range r.Findings {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSV writes a finding per row, the lists are space separated
+func CSV(w io.Writer, r *Report) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"fetched_at", "id", "name", "url", "title", "user", "date", "syntax", "source", "severity",
+		"rules", "tags", "triage", "legal_hold", "emails", "domains", "ipv4", "ipv6", "urls", "hashes", "bitcoin", "credentials"})
+	for _, f := range r.Findings {
+		var ruleNames []string
+		for _, m := range f.Rules {
+			ruleNames = append(ruleNames, m.Rule)
+		}
+		in := &indicators.Indicators{}
+		if f.Indicators != nil {
+			in = f.Indicators
+		}
+		credentials := ""
+		if f.Combos != nil {
+			credentials = strconv.Itoa(f.Combos.Total)
+		}
+		c.Write([]string{
+			f.FetchedAt.Format(time.RFC3339), f.ID, f.Name, f.FullURL, f.Title, f.User, f.Date, f.Syntax, f.Source,
+			severity(f), strings.Join(unique(ruleNames), " "), strings.Join(f.Tags, " "), f.Triage, strconv.FormatBool(f.LegalHold),
+			strings.Join(in.Emails, " "), strings.Join(in.Domains, " "), strings.Join(in.IPv4, " "), strings.Join(in.IPv6, " "),
+			strings.Join(in.URLs, " "), strings.Join(append(append(append([]string(nil), in.MD5...), in.SHA1...), in.SHA256...), " "),
+			strings.Join(in.Bitcoin, " "), credentials,
+		})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// Highest severity of the matching rules of a finding
+func severity(f *Finding) string {
+	s := f.Severity
+	for _, m := range f.Rules {
+		if rules.SeverityLevel(m.Severity) > rules.SeverityLevel(s) {
+			s = m.Severity
+		}
+	}
+	return s
+}
+
+func unique(list []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Findings of a rule in the reports
+type group struct {
+	Severity string
+	Rule     string
+	Items    []item
+}
+
+// Finding in the group of one of its rules, with the matches of the rule: on the title, the content or the layers
+type item struct {
+	*Finding
+	Matches []Match
+}
+
+// Group the findings by severity, the highest first, and by rule: a bin matching more rules is in each group
+func groups(r *Report) []*group {
+	index := map[string]*group{}
+	var out []*group
+	for _, f := range r.Findings {
+		for _, m := range f.Rules {
+			key := m.Severity + "\x00" + m.Rule
+			g := index[key]
+			if g == nil {
+				g = &group{Severity: m.Severity, Rule: m.Rule}
+				index[key] = g
+				out = append(out, g)
+			}
+			if n := len(g.Items); n > 0 && g.Items[n-1].Finding == f {
+				g.Items[n-1].Matches = append(g.Items[n-1].Matches, m)
+				continue
+			}
+			g.Items = append(g.Items, item{f, []Match{m}})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if a, b := rules.SeverityLevel(out[i].Severity), rules.SeverityLevel(out[j].Severity); a != b {
+			return a > b
+		}
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
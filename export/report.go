@@ -0,0 +1,184 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Header of a finding in the reports: its title, or its key for the bins without title
+func heading(f *Finding) string {
+	if f.Title != "" {
+		return f.Title
+	}
+	if f.Key != "" {
+		return f.Key
+	}
+	return f.Name
+}
+
+// Details of a finding in the reports
+func details(f *Finding) []string {
+	var out []string
+	add := func(label string, value string) {
+		if value != "" {
+			out = append(out, label+": "+value)
+		}
+	}
+	add("URL", f.FullURL)
+	add("User", f.User)
+	add("Source", f.Source)
+	if !f.FetchedAt.IsZero() {
+		add("Fetched", f.FetchedAt.Format(time.RFC3339))
+	}
+	add("Triage", f.Triage)
+	if f.LegalHold {
+		add("Legal hold", "yes")
+	}
+	if len(f.Tags) > 0 {
+		add("Tags", strings.Join(f.Tags, ", "))
+	}
+	if in := f.Indicators; in != nil {
+		add("Emails", strings.Join(in.Emails, ", "))
+		add("Domains", strings.Join(in.Domains, ", "))
+		add("IPs", strings.Join(append(append([]string(nil), in.IPv4...), in.IPv6...), ", "))
+		add("URLs", strings.Join(in.URLs, ", "))
+	}
+	if f.Combos != nil {
+		add("Credentials", fmt.Sprint(f.Combos.Total))
+	}
+	return out
+}
+
+// Title of a group: 'severity: rule'
+func (g *group) title() string {
+	if g.Severity == "" {
+		return g.Rule
+	}
+	return g.Severity + ": " + g.Rule
+}
+
+// Summary of a report: the number of findings and the filters
+func summary(r *Report) string {
+	s := fmt.Sprintf("%d findings, generated %s", len(r.Findings), r.Generated.Format(time.RFC3339))
+	if r.Filters != "" {
+		s += ", " + r.Filters
+	}
+	return s
+}
+
+// Markdown writes a report grouped by severity and rule, with the snippets in code blocks
+func Markdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+	b.WriteString("# pastego report\n\n" + mdEscape(summary(r)) + "\n")
+	for _, g := range groups(r) {
+		fmt.Fprintf(&b, "\n## %s (%d)\n", mdEscape(g.title()), len(g.Items))
+		for _, it := range g.Items {
+			fmt.Fprintf(&b, "\n### %s\n\n", mdEscape(heading(it.Finding)))
+			for _, d := range details(it.Finding) {
+				b.WriteString("- " + mdEscape(d) + "\n")
+			}
+			for _, m := range it.Matches {
+				if m.Where != "" {
+					b.WriteString("\nMatching in " + mdEscape(m.Where) + ":\n")
+				}
+				for _, s := range m.Snippets {
+					fence := strings.Repeat("`", maxRun(s.Text, '`')+3)
+					b.WriteString("\n" + fence + "\n" + s.Text + "\n" + fence + "\n")
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var mdReplacer = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "\n", " ",
+)
+
+// Escape the markdown characters of a text
+func mdEscape(s string) string {
+	return mdReplacer.Replace(s)
+}
+
+// Longest run of a character, to fence the snippets
+func maxRun(s string, c rune) int {
+	max, n := 0, 0
+	for _, r := range s {
+		if r == c {
+			n++
+			if n > max {
+				max = n
+			}
+		} else {
+			n = 0
+		}
+	}
+	return max
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"heading": heading,
+	"details": details,
+	"summary": summary,
+	"mark":    mark,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pastego report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h2.critical { color: #a0a; } h2.high { color: #c00; } h2.medium { color: #c80; } h2.low { color: #080; }
+pre { background: #f4f4f4; padding: .5em; white-space: pre-wrap; word-break: break-all; }
+mark { background: #ff0; }
+</style>
+</head>
+<body>
+<h1>pastego report</h1>
+<p>{{summary .Report}}</p>
+{{range .Groups}}<h2 class="{{.Severity}}">{{.Title}} ({{len .Items}})</h2>
+{{range .Items}}<h3>{{heading .Finding}}</h3>
+<ul>{{range details .Finding}}<li>{{.}}</li>{{end}}</ul>
+{{range .Matches}}{{if .Where}}<p>Matching in {{.Where}}:</p>
+{{end}}{{range .Snippets}}<pre>{{mark .}}</pre>
+{{end}}{{end}}{{end}}{{end}}</body>
+</html>
+`))
+
+// Group of the HTML template
+type htmlGroup struct {
+	*group
+	Title string
+}
+
+// HTML writes a report grouped by severity and rule, with the terms highlighted in the snippets
+func HTML(w io.Writer, r *Report) error {
+	var gs []htmlGroup
+	for _, g := range groups(r) {
+		gs = append(gs, htmlGroup{g, g.title()})
+	}
+	return htmlReport.Execute(w, struct {
+		Report *Report
+		Groups []htmlGroup
+	}{r, gs})
+}
+
+// Snippet escaped with its hits in <mark>
+func mark(s Snippet) template.HTML {
+	var b strings.Builder
+	last := 0
+	for _, h := range s.Hits {
+		if h.Start < last || h.End > len(s.Text) {
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(s.Text[last:h.Start]))
+		b.WriteString("<mark>" + template.HTMLEscapeString(s.Text[h.Start:h.End]) + "</mark>")
+		last = h.End
+	}
+	b.WriteString(template.HTMLEscapeString(s.Text[last:]))
+	return template.HTML(b.String())
+}
@@ -0,0 +1,111 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notdodo/pastego/export"
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/indicators"
+	"github.com/notdodo/pastego/pegmatch"
+)
+
+func TestSnippets(t *testing.T) {
+	text := "aaaa password bbbb password cccc èèèè secret dddd"
+	spans := []pegmatch.Span{{Start: 19, End: 27}, {Start: 5, End: 13}, {Start: 42, End: 48}}
+	got := export.Snippets(text, spans, 6)
+	want := []export.Snippet{
+		{Text: "aaaa password bbbb password cccc ", Hits: []pegmatch.Span{{Start: 5, End: 13}, {Start: 19, End: 27}}},
+		// Cut on the start of a rune
+		{Text: "èèè secret dddd", Hits: []pegmatch.Span{{Start: 7, End: 13}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snippets %+v, want %+v", got, want)
+	}
+	if got := export.Snippets("short", []pegmatch.Span{{Start: 3, End: 10}}, 5); len(got) != 0 {
+		t.Error("span out of the text", got)
+	}
+}
+
+func report() *export.Report {
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	leak := &export.Finding{
+		PasteMeta: &filesupport.PasteMeta{ID: "pastebin_a", Name: "corp__leak", FetchedAt: day,
+			PasteJSON:  filesupport.PasteJSON{Key: "a", Title: "leak <b>", FullURL: "https://pastebin.com/a"},
+			Indicators: &indicators.Indicators{Emails: []string{"a[@]corp[.]com"}, IPv4: []string{"10[.]0[.]0[.]1"}}},
+		Rules: []export.Match{
+			{Rule: "corp", Severity: "critical", Snippets: []export.Snippet{{Text: "mail <corp>", Hits: []pegmatch.Span{{Start: 6, End: 10}}}}},
+			{Rule: "password", Severity: "low", Where: "base64", Snippets: []export.Snippet{{Text: "password ```"}}},
+		},
+	}
+	dump := &export.Finding{
+		PasteMeta: &filesupport.PasteMeta{ID: "pastebin_b", Name: "password__b", FetchedAt: day,
+			PasteJSON: filesupport.PasteJSON{Key: "b"}},
+		Rules: []export.Match{{Rule: "password", Severity: "low", Where: "title"}},
+	}
+	return &export.Report{Generated: day, Filters: "since 2021-03-01", Findings: []*export.Finding{leak, dump}}
+}
+
+func TestFormats(t *testing.T) {
+	var b bytes.Buffer
+	if err := export.Write(&b, "jsonl", report()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || len(lines) != 2 {
+		t.Fatal("jsonl", lines, err)
+	}
+	if first["id"] != "pastebin_a" || first["indicators"] == nil || len(first["rules"].([]interface{})) != 2 {
+		t.Error("jsonl", first)
+	}
+
+	b.Reset()
+	if err := export.Write(&b, "csv", report()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatal("csv", rows, err)
+	}
+	row := map[string]string{}
+	for i, name := range rows[0] {
+		row[name] = rows[1][i]
+	}
+	if row["severity"] != "critical" || row["rules"] != "corp password" || row["emails"] != "a[@]corp[.]com" || row["ipv4"] != "10[.]0[.]0[.]1" {
+		t.Error("csv", row)
+	}
+
+	b.Reset()
+	if err := export.Write(&b, "markdown", report()); err != nil {
+		t.Fatal(err)
+	}
+	md := b.String()
+	for _, want := range []string{"## critical: corp (1)", "## low: password (2)", "### leak \\<b\\>", "### b", "Matching in base64:", "````\npassword ```\n````"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown without %q:\n%s", want, md)
+		}
+	}
+	if strings.Index(md, "critical: corp") > strings.Index(md, "low: password") {
+		t.Error("groups not sorted by severity")
+	}
+
+	b.Reset()
+	if err := export.Write(&b, "html", report()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h3>leak &lt;b&gt;</h3>", "mail &lt;<mark>corp</mark>&gt;", "2 findings, generated 2021-03-01T00:00:00Z, since 2021-03-01"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("html without %q", want)
+		}
+	}
+
+	if err := export.Write(&b, "pdf", report()); err == nil {
+		t.Error("unknown format")
+	}
+}
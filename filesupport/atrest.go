@@ -0,0 +1,147 @@
+package filesupport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Storage of the bins at rest
+var (
+	// Compress the bins with gzip
+	Compress bool
+	// AES-256 key to encrypt the bins, nil to store them in clear
+	Key []byte
+)
+
+// Permissions of the files and the folders written in the output directory
+const (
+	FileMode os.FileMode = 0600
+	DirMode  os.FileMode = 0700
+)
+
+// Header of the encoded bins: magic, flags, [nonce]
+const magic = "PGO1"
+
+const (
+	flagGzip = 1 << iota
+	flagAESGCM
+)
+
+// LoadKey reads an AES-256 key from a file: 32 raw bytes, or encoded as hex or base64
+func LoadKey(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 32 {
+		return b, nil
+	}
+	s := strings.TrimSpace(string(b))
+	if k, err := hex.DecodeString(s); err == nil && len(k) == 32 {
+		return k, nil
+	}
+	if k, err := base64.StdEncoding.DecodeString(s); err == nil && len(k) == 32 {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%s: the key must be 32 bytes, raw or encoded as hex or base64", path)
+}
+
+// Hash of the content of a bin: SHA256, or HMAC-SHA256 with the key to not disclose the content of encrypted bins
+func contentHash(text string) string {
+	if Key != nil {
+		mac := hmac.New(sha256.New, Key)
+		mac.Write([]byte(text))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Compress and encrypt a bin as configured. The header is written even for the bins stored as they are,
+// so a bin starting with the magic is never taken for an encoded one
+func encode(data []byte) ([]byte, error) {
+	var flags byte
+	if Compress {
+		flags |= flagGzip
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+	header := []byte(magic)
+	if Key != nil {
+		flags |= flagAESGCM
+		gcm, err := newGCM(Key)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		header = append(append(header, flags), nonce...)
+		// The header is authenticated too
+		return gcm.Seal(header, nonce, data, header), nil
+	}
+	return append(append(header, flags), data...), nil
+}
+
+// Decrypt and decompress a bin, the bins saved without header by the previous versions are returned as they are
+func decode(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) || len(data) < len(magic)+1 {
+		return data, nil
+	}
+	flags := data[len(magic)]
+	body := data[len(magic)+1:]
+	if flags&flagAESGCM != 0 {
+		if Key == nil {
+			return nil, errors.New("encrypted bin: missing key")
+		}
+		gcm, err := newGCM(Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) < gcm.NonceSize() {
+			return nil, errors.New("encrypted bin: truncated")
+		}
+		header := data[:len(magic)+1+gcm.NonceSize()]
+		if body, err = gcm.Open(nil, body[:gcm.NonceSize()], body[gcm.NonceSize():], header); err != nil {
+			return nil, fmt.Errorf("encrypted bin: %s", err)
+		}
+	}
+	if flags&flagGzip != 0 {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return ioutil.ReadAll(zr)
+	}
+	return body, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
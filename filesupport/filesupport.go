@@ -1,15 +1,18 @@
 package filesupport
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
-	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/notdodo/pastego/combolist"
+	"github.com/notdodo/pastego/indicators"
+	"github.com/notdodo/pastego/pegmatch"
 )
 
 type PasteJSON struct {
@@ -24,67 +27,288 @@ type PasteJSON struct {
 	User      string `json:"user,-"`
 }
 
-var logFile string
+// Metadata of a saved bin, stored as JSON in the '.meta' folder of the output directory
+type PasteMeta struct {
+	PasteJSON
+	// Identifier of the bin: its source and key
+	ID string `json:"id,omitempty"`
+	// Name shown in the list: 'match__pasteTitle'
+	Name string `json:"name,omitempty"`
+	// SHA256 of the content, the name of its file in the '.store' folder
+	Hash string `json:"hash,omitempty"`
+	// Rule used to name the bin: the first matching one
+	Match    string   `json:"match"`
+	Severity string   `json:"severity,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Layer    string   `json:"layer,omitempty"`
+	// Every matching rule
+	Matches []RuleMatch `json:"matches,omitempty"`
+	// Where the bin comes from: 'pastebin' or 'replay:<archive>'
+	Source     string                 `json:"source,omitempty"`
+	FetchedAt  time.Time              `json:"fetched_at"`
+	Indicators *indicators.Indicators `json:"indicators,omitempty"`
+	Combos     *combolist.Result      `json:"combos,omitempty"`
+	// Triage of the analyst: 'true-positive' or 'false-positive'
+	Triage string `json:"triage,omitempty"`
+	// Never delete the bin
+	LegalHold bool `json:"legal_hold,omitempty"`
+}
+
+// Triage states
+const (
+	TruePositive  = "true-positive"
+	FalsePositive = "false-positive"
+)
+
+// Rule matching a bin with the positions of its terms
+type RuleMatch struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// Decoders of the matching layer, the spans refer to the decoded text
+	Layer string `json:"layer,omitempty"`
+	// The spans refer to the normalized text
+	Normalized bool `json:"normalized,omitempty"`
+	// Matching the title of the bin instead of its content
+	Title bool            `json:"title,omitempty"`
+	Spans []pegmatch.Span `json:"spans,omitempty"`
+}
+
+// Folder, inside the output directory, holding the metadata of the saved bins
+const MetaDir = ".meta"
 
-// Log a string to a temp file
-func LogToFile(s string) {
-	var err error
-	var tmpfile *os.File
-	var t = time.Now().Format(time.RFC3339)
-	if logFile != "" {
-		tmpfile, err = os.OpenFile(logFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+// Folder, inside the output directory, holding the content of the saved bins named by its SHA256
+const StoreDir = ".store"
+
+// Name of the file of a bin: 'match__pasteTitle' or 'match__pasteKey' for untitled bins
+func fileName(link *PasteJSON, match string) string {
+	var title string
+	if link.Title == "" {
+		title += link.Key
 	} else {
-		tmpfile, err = ioutil.TempFile("", "pastego")
-		logFile = tmpfile.Name()
+		title += link.Title
+	}
+	return fmt.Sprintf("%s__", match) + govalidator.SafeFileName(strings.Replace(title, "/", "_", -1))
+}
+
+var reUnsafeID = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Identifier of a bin from its source and key, the hash of the content for the bins without key
+func binID(source string, key string, hash string) string {
+	if key == "" {
+		key = hash[:16]
+	}
+	if source == "" {
+		source = "unknown"
+	}
+	// Keys are case sensitive: SafeFileName would lower them
+	return reUnsafeID.ReplaceAllString(source+"_"+key, "_")
+}
+
+// Key of the metadata: '.meta/<id>.json', '.meta/<name>.json' for the bins saved as plain files
+func metaKey(meta *PasteMeta) string {
+	name := meta.ID
+	if name == "" {
+		name = meta.Name
+	}
+	if name == "" {
+		name = fileName(&meta.PasteJSON, meta.Match)
+	}
+	return MetaDir + "/" + name + ".json"
+}
+
+// Exists checks if the bin, or the same content, has already been saved: sets the identifier,
+// the name and the hash of the metadata. A bin edited after being saved is a new version:
+// its identifier ends with the hash of the new content
+func Exists(meta *PasteMeta, text string, store Store) bool {
+	meta.Hash = contentHash(text)
+	meta.ID = binID(meta.Source, meta.Key, meta.Hash)
+	meta.Name = fileName(&meta.PasteJSON, meta.Match)
+	// Duplicated content
+	if _, err := store.Stat(StoreDir + "/" + meta.Hash); err == nil {
+		return true
 	}
+	saved, err := ReadMeta(meta.ID, store)
 	if err != nil {
-		log.Fatalln(err)
+		return false
 	}
+	if saved.Hash == meta.Hash {
+		return true
+	}
+	meta.ID += "_" + meta.Hash[:12]
+	_, err = store.Stat(metaKey(meta))
+	return err == nil
+}
 
-	if _, err := tmpfile.Write([]byte(t + " - " + s + "\r\n")); err != nil {
-		log.Fatalln(err)
+// Save the bin and its metadata in the store: the content is stored once by its SHA256,
+// returns false if the bin, or the same content, has already been saved
+func Save(meta *PasteMeta, text string, store Store) (bool, error) {
+	if Exists(meta, text, store) {
+		return false, nil
 	}
+	data, err := encode([]byte(text))
+	if err != nil {
+		return false, err
+	}
+	if err := store.Put(StoreDir+"/"+meta.Hash, data); err != nil {
+		return false, err
+	}
+	// Without its metadata the content would block the next saves of the bin as a duplicate
+	if err := SaveMeta(meta, store); err != nil {
+		store.Delete(StoreDir + "/" + meta.Hash)
+		return false, err
+	}
+	return true, nil
+}
 
-	defer tmpfile.Close()
+// Save the metadata of a bin already saved with Save
+func SaveMeta(meta *PasteMeta, store Store) error {
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if b, err = encodeMeta(b); err != nil {
+		return err
+	}
+	return store.Put(metaKey(meta), b)
 }
 
-// Save the bin to the output directory: default is '$(pwd)/results'
-func SaveToFile(link *PasteJSON, text string, match string, outputTo string) bool {
-	// ./outputDir
-	outputDir, _ := filepath.Abs(filepath.Clean(outputTo))
-	if err := os.MkdirAll(outputDir, os.FileMode(0775)); err != nil {
-		// Error on creating/reading the output folder
-		LogToFile(err.Error())
-		log.Fatalln(err)
+// Encrypt the metadata when a key is set: they hold the indicators and the titles of the bins.
+// Without key they are kept as plain JSON
+func encodeMeta(b []byte) ([]byte, error) {
+	if Key == nil {
+		return b, nil
 	}
-	// match - pasteTitle
-	var title string
-	if link.Title == "" {
-		title += link.Key
-	} else {
-		title += link.Title
+	return encode(b)
+}
+
+// EncodeMeta marshals the metadata of a bin, encrypted as the files of the '.meta' folder
+func EncodeMeta(meta *PasteMeta) ([]byte, error) {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	return encodeMeta(b)
+}
+
+// DecodeMeta unmarshals the metadata encoded by EncodeMeta or saved by SaveMeta
+func DecodeMeta(b []byte) (*PasteMeta, error) {
+	b, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	meta := &PasteMeta{}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return nil, err
 	}
-	title = fmt.Sprintf("%s__", match) + govalidator.SafeFileName(strings.Replace(title, "/", "_", -1))
-	// ./outputDir/match - pasteTitle
-	filePath := outputDir + string(filepath.Separator) + title
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		if err := ioutil.WriteFile(filePath, []byte(text), 0644); err != nil {
-			// Error on writing file, something went wrong
-			LogToFile(err.Error())
-			log.Fatalln(err)
+	return meta, nil
+}
+
+// List the saved bins sorted by name, with the bins saved as plain files by the previous versions
+func List(store Store) ([]*PasteMeta, error) {
+	var list []*PasteMeta
+	files, err := store.List("")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		// Temporary files of the writes
+		if strings.HasPrefix(f, ".") {
+			continue
 		}
-		return true
+		meta, err := ReadMeta(f, store)
+		if err != nil {
+			meta = &PasteMeta{}
+		}
+		meta.ID, meta.Hash, meta.Name = "", "", f
+		list = append(list, meta)
 	}
-	return false
+	metas, _ := store.List(MetaDir + "/")
+	for _, f := range metas {
+		if !strings.HasSuffix(f, ".json") {
+			continue
+		}
+		meta, err := ReadMeta(strings.TrimSuffix(keyName(f), ".json"), store)
+		// Skip the metadata of the plain files
+		if err != nil || meta.Hash == "" {
+			continue
+		}
+		list = append(list, meta)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
 }
 
-// Delete a file when is not interesting
-func DeleteFile(l string, baseDir string) error {
-	f, _ := filepath.Abs(baseDir + string(filepath.Separator) + l)
-	if _, err := os.Stat(f); !os.IsNotExist(err) {
-		if err := os.Remove(f); err != nil {
-			return err
+// ListIDs lists the IDs of the bins with metadata in the store without reading them,
+// with the names of the plain files of the previous versions having metadata
+func ListIDs(store Store) ([]string, error) {
+	metas, err := store.List(MetaDir + "/")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range metas {
+		if strings.HasSuffix(f, ".json") {
+			ids = append(ids, strings.TrimSuffix(keyName(f), ".json"))
 		}
 	}
+	return ids, nil
+}
+
+// Key of the content of a bin: in the storage, or the plain file of the previous versions
+func binKey(meta *PasteMeta) string {
+	if meta.Hash != "" {
+		return StoreDir + "/" + meta.Hash
+	}
+	return meta.Name
+}
+
+// Read the content of a saved bin, decrypted and decompressed
+func ReadBin(meta *PasteMeta, store Store) (string, error) {
+	b, err := store.Get(binKey(meta))
+	if err != nil {
+		return "", err
+	}
+	b, err = decode(b)
+	return string(b), err
+}
+
+// Size of the stored content of a saved bin, compressed and encrypted
+func Size(meta *PasteMeta, store Store) (int64, error) {
+	return store.Stat(binKey(meta))
+}
+
+// Read the metadata of a saved bin, 'l' is the name of the file
+func ReadMeta(l string, store Store) (*PasteMeta, error) {
+	b, err := store.Get(MetaDir + "/" + l + ".json")
+	if err != nil {
+		return nil, err
+	}
+	return DecodeMeta(b)
+}
+
+// Delete a bin when is not interesting: its content and its metadata
+func Delete(meta *PasteMeta, store Store) error {
+	if meta.Hash == "" {
+		return DeleteFile(meta.Name, store)
+	}
+	if err := store.Delete(binKey(meta)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := store.Delete(metaKey(meta)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Delete a file when is not interesting
+func DeleteFile(l string, store Store) error {
+	if err := store.Delete(l); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// Remove the metadata too, if any
+	if err := store.Delete(MetaDir + "/" + l + ".json"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,123 @@
+package filesupport
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Store of the saved bins. Keys are slash separated and relative to the root of the store,
+// i.e. '.store/<hash>' or '.meta/<id>.json'. Missing keys return an error satisfying os.IsNotExist
+type Store interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	// Size of the data of a key
+	Stat(key string) (int64, error)
+	// Keys directly under a prefix ending with '/', or in the root with an empty prefix
+	List(prefix string) ([]string, error)
+	Delete(key string) error
+	// Location of the store, for the messages
+	String() string
+}
+
+// Local store: the keys are files of a folder
+type Local struct {
+	Dir string
+}
+
+// NewLocal returns the store of a folder
+func NewLocal(dir string) *Local {
+	dir, _ = filepath.Abs(filepath.Clean(dir))
+	return &Local{Dir: dir}
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+// Put writes the file of a key, creating its folders
+func (l *Local) Put(key string, data []byte) error {
+	p := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), DirMode); err != nil {
+		return err
+	}
+	return WriteFile(p, data, FileMode)
+}
+
+// Get reads the file of a key
+func (l *Local) Get(key string) ([]byte, error) {
+	return ioutil.ReadFile(l.path(key))
+}
+
+// Stat returns the size of the file of a key
+func (l *Local) Stat(key string) (int64, error) {
+	info, err := os.Stat(l.path(key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// List the files of a folder, the subfolders are skipped
+func (l *Local) List(prefix string) ([]string, error) {
+	files, err := ioutil.ReadDir(l.path(prefix))
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, f := range files {
+		if !f.IsDir() {
+			keys = append(keys, prefix+f.Name())
+		}
+	}
+	return keys, nil
+}
+
+// Delete removes the file of a key
+func (l *Local) Delete(key string) error {
+	return os.Remove(l.path(key))
+}
+
+func (l *Local) String() string {
+	return l.Dir
+}
+
+// WriteFile writes a file atomically: the data is written and synced to a temporary file of the same folder,
+// then renamed. A failed write leaves the previous file, if any, and no partial files
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// Persist the rename
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// Name of a key without its prefix
+func keyName(key string) string {
+	return key[strings.LastIndex(key, "/")+1:]
+}
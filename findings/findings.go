@@ -0,0 +1,373 @@
+package findings
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+
+	// import third party libraries
+	bolt "go.etcd.io/bbolt"
+)
+
+// File of the database inside the metadata folder of the output directory
+const FileName = "findings.db"
+
+var (
+	bucketBins = []byte("bins")
+	// Indexes: '<value>\x00<fetch time><id>'
+	bucketTime   = []byte("by_time")
+	bucketRule   = []byte("by_rule")
+	bucketSource = []byte("by_source")
+	bucketUser   = []byte("by_user")
+	bucketTag    = []byte("by_tag")
+)
+
+// DB of the saved bins: their metadata, matching rules, indicators and triage.
+// The file is locked only during a transaction, so the scraper, the TUI and the commands can share it:
+// the readers together, the writers one at a time
+type DB struct {
+	path     string
+	readOnly bool
+	// Key of the HMAC of the IDs of the bins, of the values of the indexes and of the terms of the search index,
+	// nil to store them in clear
+	IndexKey []byte
+}
+
+// How long a transaction waits for the lock held by another process
+var LockTimeout = 10 * time.Second
+
+// Errors of the transactions
+var (
+	ErrLocked   = errors.New("locked by another pastego process")
+	ErrReadOnly = errors.New("opened read-only")
+)
+
+// Query of the bins, empty fields match everything
+type Query struct {
+	Rule   string
+	Source string
+	User   string
+	Tag    string
+	// Fetch time range, inclusive
+	Since time.Time
+	Until time.Time
+	// Triage state, see filesupport.TruePositive
+	Triage string
+	// Maximum number of results, 0 for all
+	Limit int
+}
+
+// Open the database, creating it if missing
+func Open(path string) (*DB, error) {
+	d := &DB{path: path}
+	err := d.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketBins, bucketTime, bucketRule, bucketSource, bucketUser, bucketTag, bucketTrigrams, bucketBinTrigrams} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// OpenReadOnly opens an existing database for the queries and the searches: the writes fail with ErrReadOnly
+func OpenReadOnly(path string) (*DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return &DB{path: path, readOnly: true}, nil
+}
+
+// Close the database: nothing is kept open between the transactions
+func (d *DB) Close() error {
+	return nil
+}
+
+// Run a read transaction, sharing the lock with the other readers
+func (d *DB) view(fn func(*bolt.Tx) error) error {
+	b, err := d.open(true)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return b.View(fn)
+}
+
+// Run a write transaction, with the exclusive lock
+func (d *DB) update(fn func(*bolt.Tx) error) error {
+	if d.readOnly {
+		return fmt.Errorf("%s: %w", d.path, ErrReadOnly)
+	}
+	b, err := d.open(false)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return b.Update(fn)
+}
+
+func (d *DB) open(readOnly bool) (*bolt.DB, error) {
+	b, err := bolt.Open(d.path, 0600, &bolt.Options{Timeout: LockTimeout, ReadOnly: readOnly})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("%s: %w", d.path, ErrLocked)
+	}
+	return b, err
+}
+
+// Count the bins
+func (d *DB) Count() int {
+	n := 0
+	d.view(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucketBins).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Put adds or updates a bin, by its ID
+func (d *DB) Put(meta *filesupport.PasteMeta) error {
+	b, err := filesupport.EncodeMeta(meta)
+	if err != nil {
+		return err
+	}
+	return d.update(func(tx *bolt.Tx) error {
+		id := d.hash(meta.ID)
+		if err := d.unindex(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketBins).Put(id, b); err != nil {
+			return err
+		}
+		for bucket, values := range indexValues(meta) {
+			for _, v := range values {
+				if err := tx.Bucket([]byte(bucket)).Put(d.indexKey(v, meta), nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Get a bin by its ID, nil if missing
+func (d *DB) Get(id string) (*filesupport.PasteMeta, error) {
+	var meta *filesupport.PasteMeta
+	err := d.view(func(tx *bolt.Tx) error {
+		var err error
+		meta, err = get(tx, d.hash(id))
+		return err
+	})
+	return meta, err
+}
+
+// Missing returns the IDs of the bins not in the database
+func (d *DB) Missing(ids []string) ([]string, error) {
+	var out []string
+	err := d.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketBins)
+		for _, id := range ids {
+			if b.Get(d.hash(id)) == nil {
+				out = append(out, id)
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Delete a bin by its ID
+func (d *DB) Delete(id string) error {
+	return d.update(func(tx *bolt.Tx) error {
+		key := d.hash(id)
+		if err := d.unindex(tx, key); err != nil {
+			return err
+		}
+		if err := d.unindexText(tx, key); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketBins).Delete(key)
+	})
+}
+
+// Query the bins, the most recent first
+func (d *DB) Query(q Query) ([]*filesupport.PasteMeta, error) {
+	// Scan the most selective index
+	bucket, prefix := bucketTime, []byte{}
+	switch {
+	case q.Rule != "":
+		bucket, prefix = bucketRule, d.indexPrefix(q.Rule)
+	case q.Tag != "":
+		bucket, prefix = bucketTag, d.indexPrefix(q.Tag)
+	case q.User != "":
+		bucket, prefix = bucketUser, d.indexPrefix(q.User)
+	case q.Source != "":
+		bucket, prefix = bucketSource, d.indexPrefix(q.Source)
+	}
+	var out []*filesupport.PasteMeta
+	err := d.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		// Start from the end of the range and go back in time
+		var k []byte
+		if q.Until.IsZero() {
+			k = seekLast(c, append(append([]byte{}, prefix...), bytes.Repeat([]byte{0xff}, 9)...))
+		} else {
+			k = seekLast(c, append(append(append([]byte{}, prefix...), timeKey(q.Until)...), 0xff))
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Prev() {
+			if len(k) < len(prefix)+8 {
+				continue
+			}
+			if !q.Since.IsZero() && bytes.Compare(k[len(prefix):len(prefix)+8], timeKey(q.Since)) < 0 {
+				break
+			}
+			meta, err := get(tx, k[len(prefix)+8:])
+			if err != nil {
+				return err
+			}
+			if meta == nil || !q.matches(meta) {
+				continue
+			}
+			out = append(out, meta)
+			if q.Limit > 0 && len(out) >= q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Check all the conditions of the query on a bin
+func (q *Query) matches(meta *filesupport.PasteMeta) bool {
+	values := indexValues(meta)
+	has := func(bucket []byte, v string) bool {
+		if v == "" {
+			return true
+		}
+		for _, x := range values[string(bucket)] {
+			if x == v {
+				return true
+			}
+		}
+		return false
+	}
+	t := meta.FetchedAt
+	return has(bucketRule, q.Rule) && has(bucketTag, q.Tag) && has(bucketUser, q.User) && has(bucketSource, q.Source) &&
+		(q.Triage == "" || meta.Triage == q.Triage) &&
+		(q.Since.IsZero() || !t.Before(q.Since)) && (q.Until.IsZero() || !t.After(q.Until))
+}
+
+// Position the cursor on the last key lower or equal than 'key'
+func seekLast(c *bolt.Cursor, key []byte) []byte {
+	k, _ := c.Seek(key)
+	if k == nil {
+		k, _ = c.Last()
+		return k
+	}
+	if bytes.Compare(k, key) > 0 {
+		k, _ = c.Prev()
+	}
+	return k
+}
+
+func get(tx *bolt.Tx, id []byte) (*filesupport.PasteMeta, error) {
+	b := tx.Bucket(bucketBins).Get(id)
+	if b == nil {
+		return nil, nil
+	}
+	return filesupport.DecodeMeta(b)
+}
+
+// Remove the index entries of a bin
+func (d *DB) unindex(tx *bolt.Tx, id []byte) error {
+	old, err := get(tx, id)
+	if err != nil || old == nil {
+		return err
+	}
+	for bucket, values := range indexValues(old) {
+		for _, v := range values {
+			if err := tx.Bucket([]byte(bucket)).Delete(d.indexKey(v, old)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Values of each index of a bin: all the matching rules and their tags
+func indexValues(meta *filesupport.PasteMeta) map[string][]string {
+	values := map[string][]string{string(bucketTime): {""}}
+	add := func(bucket []byte, v string) {
+		if v == "" {
+			return
+		}
+		for _, x := range values[string(bucket)] {
+			if x == v {
+				return
+			}
+		}
+		values[string(bucket)] = append(values[string(bucket)], v)
+	}
+	add(bucketRule, meta.Match)
+	add(bucketSource, meta.Source)
+	add(bucketUser, meta.User)
+	for _, t := range meta.Tags {
+		add(bucketTag, t)
+	}
+	for _, m := range meta.Matches {
+		add(bucketRule, m.Rule)
+		for _, t := range m.Tags {
+			add(bucketTag, t)
+		}
+	}
+	return values
+}
+
+// Length of the IDs and of the index values hashed with IndexKey
+const hashedValueSize = 16
+
+// Key of an ID or of an index value: the value itself or its truncated HMAC
+func (d *DB) hash(v string) []byte {
+	if d.IndexKey == nil {
+		return []byte(v)
+	}
+	return d.mac(v, hashedValueSize)
+}
+
+func (d *DB) mac(v string, size int) []byte {
+	mac := hmac.New(sha256.New, d.IndexKey)
+	mac.Write([]byte(v))
+	return mac.Sum(nil)[:size]
+}
+
+// Prefix of the keys of an index value, empty for the time index
+func (d *DB) indexPrefix(v string) []byte {
+	if v == "" {
+		return []byte{}
+	}
+	return append(d.hash(v), 0)
+}
+
+func (d *DB) indexKey(v string, meta *filesupport.PasteMeta) []byte {
+	return append(append(d.indexPrefix(v), timeKey(meta.FetchedAt)...), d.hash(meta.ID)...)
+}
+
+// Sortable representation of a time
+func timeKey(t time.Time) []byte {
+	k := make([]byte, 8)
+	if !t.IsZero() {
+		binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	}
+	return k
+}
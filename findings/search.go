@@ -0,0 +1,228 @@
+package findings
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/pegmatch"
+
+	// import third party libraries
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// Inverted index: '<trigram><fetch time><id>'
+	bucketTrigrams = []byte("trigrams")
+	// Trigrams of each bin, to remove them from the index
+	bucketBinTrigrams = []byte("bin_trigrams")
+)
+
+// Length of the sequences of the index: the terms of the expressions are substrings, not words
+const trigramSize = 3
+
+// Length of the terms of the index hashed with IndexKey
+const hashedTermSize = 8
+
+// Term of the index of a trigram: the trigram itself or its truncated HMAC
+func (d *DB) term(t string) []byte {
+	if d.IndexKey == nil {
+		return []byte(t)
+	}
+	return d.mac(t, hashedTermSize)
+}
+
+func (d *DB) termSize() int {
+	if d.IndexKey == nil {
+		return trigramSize
+	}
+	return hashedTermSize
+}
+
+// Unique lower case trigrams of a text
+func trigrams(text string) []string {
+	var out []string
+	seen := map[string]bool{}
+	text = strings.ToLower(text)
+	for i := 0; i+trigramSize <= len(text); i++ {
+		t := text[i : i+trigramSize]
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Index the text of a bin for Search: call it after Put
+func (d *DB) Index(meta *filesupport.PasteMeta, text string) error {
+	return d.update(func(tx *bolt.Tx) error {
+		id := d.hash(meta.ID)
+		if err := d.unindexText(tx, id); err != nil {
+			return err
+		}
+		suffix := append(timeKey(meta.FetchedAt), id...)
+		terms := timeKey(meta.FetchedAt)
+		b := tx.Bucket(bucketTrigrams)
+		for _, t := range trigrams(text) {
+			term := d.term(t)
+			if err := b.Put(append(term, suffix...), nil); err != nil {
+				return err
+			}
+			terms = append(terms, term...)
+		}
+		return tx.Bucket(bucketBinTrigrams).Put(id, terms)
+	})
+}
+
+// Remove a bin from the inverted index
+func (d *DB) unindexText(tx *bolt.Tx, id []byte) error {
+	v := tx.Bucket(bucketBinTrigrams).Get(id)
+	if v == nil {
+		return nil
+	}
+	v = append([]byte{}, v...)
+	suffix := append(v[:8:8], id...)
+	b := tx.Bucket(bucketTrigrams)
+	size := d.termSize()
+	for i := 8; i+size <= len(v); i += size {
+		if err := b.Delete(append(append([]byte{}, v[i:i+size]...), suffix...)); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(bucketBinTrigrams).Delete(id)
+}
+
+// Candidates read by a transaction of Search: the lock of the database is not held while 'verify' runs
+const searchBatch = 100
+
+// Search the bins matching an expression, the most recent first: the index selects the bins that may match
+// and 'verify' checks the expression on each of them, outside of the transactions
+func (d *DB) Search(expr string, limit int, verify func(*filesupport.PasteMeta) bool) ([]*filesupport.PasteMeta, error) {
+	// The structure of the expression does not depend on the content
+	pegmatch.Lock.Lock()
+	content := pegmatch.PasteContentString
+	pegmatch.PasteContentString = ""
+	tree, err := pegmatch.Explain(expr)
+	pegmatch.PasteContentString = content
+	pegmatch.Lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var out []*filesupport.PasteMeta
+	var ids map[string]bool
+	// Last key of the time index read, nil before the first batch
+	var last []byte
+	for {
+		var batch []*filesupport.PasteMeta
+		done := false
+		err := d.view(func(tx *bolt.Tx) error {
+			// Newest first, by the time index
+			c := tx.Bucket(bucketTime).Cursor()
+			var k []byte
+			if last == nil {
+				ids = d.candidates(tx, tree)
+				k, _ = c.Last()
+			} else if k, _ = c.Seek(last); k == nil {
+				k, _ = c.Last()
+			} else {
+				k, _ = c.Prev()
+			}
+			for ; k != nil && len(batch) < searchBatch; k, _ = c.Prev() {
+				last = append(last[:0], k...)
+				id := k[8:]
+				if ids != nil && !ids[string(id)] {
+					continue
+				}
+				meta, err := get(tx, id)
+				if err == nil && meta != nil {
+					batch = append(batch, meta)
+				}
+			}
+			done = k == nil
+			return nil
+		})
+		if err != nil {
+			return out, err
+		}
+		for _, meta := range batch {
+			if verify(meta) {
+				out = append(out, meta)
+				if limit > 0 && len(out) >= limit {
+					return out, nil
+				}
+			}
+		}
+		if done {
+			return out, nil
+		}
+	}
+}
+
+// Bins that may match a node of the expression, nil for all the bins
+func (d *DB) candidates(tx *bolt.Tx, n *pegmatch.Node) map[string]bool {
+	switch n.Op {
+	case "&&":
+		var ids map[string]bool
+		for _, c := range n.Children {
+			ids = intersect(ids, d.candidates(tx, c))
+		}
+		return ids
+	case "||":
+		ids := map[string]bool{}
+		for _, c := range n.Children {
+			cids := d.candidates(tx, c)
+			if cids == nil {
+				return nil
+			}
+			for id := range cids {
+				ids[id] = true
+			}
+		}
+		return ids
+	case "~":
+		return nil
+	}
+	// Approximate terms and functions can match anything
+	term := n.Text
+	if strings.HasPrefix(term, "~") || strings.Contains(term, "(") {
+		return nil
+	}
+	term = strings.Trim(term, "'")
+	if len(term) < trigramSize {
+		return nil
+	}
+	var ids map[string]bool
+	for _, t := range trigrams(term) {
+		ids = intersect(ids, d.posting(tx, t))
+	}
+	return ids
+}
+
+// Bins containing a trigram
+func (d *DB) posting(tx *bolt.Tx, t string) map[string]bool {
+	ids := map[string]bool{}
+	prefix := d.term(t)
+	c := tx.Bucket(bucketTrigrams).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		ids[string(k[len(prefix)+8:])] = true
+	}
+	return ids
+}
+
+// Intersection of two sets, nil is the set of all the bins
+func intersect(a map[string]bool, b map[string]bool) map[string]bool {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	out := map[string]bool{}
+	for id := range a {
+		if b[id] {
+			out[id] = true
+		}
+	}
+	return out
+}
@@ -0,0 +1,294 @@
+package findings_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/findings"
+	"github.com/notdodo/pastego/indicators"
+
+	// import third party libraries
+	bolt "go.etcd.io/bbolt"
+)
+
+func ids(list []*filesupport.PasteMeta) []string {
+	var out []string
+	for _, m := range list {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestQuery(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	db, err := findings.Open(filepath.Join(dir, findings.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	bins := []*filesupport.PasteMeta{
+		{ID: "a", Match: "password", Source: "pastebin", Tags: []string{"creds"}, FetchedAt: day},
+		{ID: "b", Match: "corp", Source: "pastebin", FetchedAt: day.Add(time.Hour),
+			Matches: []filesupport.RuleMatch{{Rule: "corp"}, {Rule: "password", Tags: []string{"creds"}}}},
+		{ID: "c", Match: "corp", Source: "replay:old.jsonl", FetchedAt: day.Add(48 * time.Hour)},
+	}
+	bins[1].User = "mallory"
+	for _, b := range bins {
+		if err := db.Put(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queries := []struct {
+		q    findings.Query
+		want string
+	}{
+		{findings.Query{}, "c,b,a"},
+		{findings.Query{Rule: "password"}, "b,a"},
+		{findings.Query{Rule: "password", User: "mallory"}, "b"},
+		{findings.Query{Rule: "corp", Since: day.Add(time.Minute)}, "c,b"},
+		{findings.Query{Tag: "creds", Until: day}, "a"},
+		{findings.Query{Source: "replay:old.jsonl"}, "c"},
+		{findings.Query{Since: day, Until: day.Add(24 * time.Hour)}, "b,a"},
+		{findings.Query{Limit: 2}, "c,b"},
+	}
+	for _, q := range queries {
+		list, err := db.Query(q.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(ids(list), ","); got != q.want {
+			t.Error(q.want, q.q, got)
+		}
+	}
+
+	// Updates move the index entries
+	bins[0].Triage = filesupport.TruePositive
+	bins[0].Tags = nil
+	db.Put(bins[0])
+	if list, _ := db.Query(findings.Query{Tag: "creds"}); len(list) != 1 || list[0].ID != "b" {
+		t.Error("tags not updated", ids(list))
+	}
+	if list, _ := db.Query(findings.Query{Triage: filesupport.TruePositive}); len(list) != 1 || list[0].ID != "a" {
+		t.Error("triage", ids(list))
+	}
+	db.Delete("b")
+	if list, _ := db.Query(findings.Query{Rule: "password"}); len(list) != 1 || db.Count() != 2 {
+		t.Error("not deleted", ids(list))
+	}
+	if meta, err := db.Get("c"); err != nil || meta == nil || meta.Source != "replay:old.jsonl" {
+		t.Error("get", meta, err)
+	}
+	if missing, err := db.Missing([]string{"a", "b", "c", "d"}); err != nil || strings.Join(missing, ",") != "b,d" {
+		t.Error("missing", missing, err)
+	}
+}
+
+func TestShared(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, findings.FileName)
+	if _, err := findings.OpenReadOnly(path); !os.IsNotExist(err) {
+		t.Error("missing database", err)
+	}
+	// The scraper and a command open the same database
+	db, err := findings.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := findings.OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put(&filesupport.PasteMeta{ID: "a", Match: "password"})
+	if list, err := reader.Query(findings.Query{Rule: "password"}); err != nil || len(list) != 1 {
+		t.Error("query", list, err)
+	}
+	if err := reader.Put(&filesupport.PasteMeta{ID: "b"}); !errors.Is(err, findings.ErrReadOnly) {
+		t.Error("write on read-only", err)
+	}
+	if err := db.Put(&filesupport.PasteMeta{ID: "b"}); err != nil || reader.Count() != 2 {
+		t.Error("write", err)
+	}
+
+	// Lock held by a transaction of another process
+	defer func(timeout time.Duration) { findings.LockTimeout = timeout }(findings.LockTimeout)
+	findings.LockTimeout = 100 * time.Millisecond
+	other, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := reader.Query(findings.Query{}); !errors.Is(err, findings.ErrLocked) {
+		t.Error("locked", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	testSearch(t, nil)
+	// Terms of the index hashed with a key
+	testSearch(t, []byte("0123456789abcdef0123456789abcdef"))
+}
+
+func testSearch(t *testing.T, key []byte) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	db, err := findings.Open(filepath.Join(dir, findings.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.IndexKey = key
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	texts := map[string]string{
+		"a": "my password is here",
+		"b": "the PASSWORD of root, php",
+		"c": "nothing to see",
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		meta := &filesupport.PasteMeta{ID: id, FetchedAt: day.Add(time.Duration(i) * time.Hour)}
+		db.Put(meta)
+		if err := db.Index(meta, texts[id]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Verify with a case-insensitive substring search, counting the bins read
+	read := 0
+	search := func(expr string, terms ...string) string {
+		read = 0
+		list, err := db.Search(expr, 0, func(meta *filesupport.PasteMeta) bool {
+			read++
+			for _, term := range terms {
+				if !strings.Contains(strings.ToLower(texts[meta.ID]), term) {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(ids(list), ",")
+	}
+	if got := search("password", "password"); got != "b,a" || read != 2 {
+		t.Error("term", got, read)
+	}
+	if got := search("password && root", "password", "root"); got != "b" || read != 1 {
+		t.Error("and", got, read)
+	}
+	if got := search("root || 'to see'"); got != "c,b" || read != 2 {
+		t.Error("or", got, read)
+	}
+	// Negations and short terms can not use the index
+	if got := search("~php"); got != "c,b,a" || read != 3 {
+		t.Error("not", got, read)
+	}
+	if _, err := db.Search("password &&", 0, nil); err == nil {
+		t.Error("invalid expression accepted")
+	}
+
+	db.Delete("a")
+	if got := search("password"); got != "b" || read != 1 {
+		t.Error("deleted", got, read)
+	}
+}
+
+func TestSearchWrites(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	db, err := findings.Open(filepath.Join(dir, findings.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(timeout time.Duration) { findings.LockTimeout = timeout }(findings.LockTimeout)
+	findings.LockTimeout = 100 * time.Millisecond
+
+	// More bins than a batch of candidates
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 250; i++ {
+		meta := &filesupport.PasteMeta{ID: fmt.Sprintf("%03d", i), FetchedAt: day.Add(time.Duration(i) * time.Minute)}
+		db.Put(meta)
+		db.Index(meta, "password "+meta.ID)
+	}
+	// The scraper saves the bins while the search reads them
+	written := 0
+	list, err := db.Search("password", 0, func(meta *filesupport.PasteMeta) bool {
+		if err := db.Put(&filesupport.PasteMeta{ID: "new" + meta.ID, FetchedAt: day.AddDate(1, 0, 0)}); err != nil {
+			t.Fatal("write during the search", err)
+		}
+		written++
+		return meta.ID != "100"
+	})
+	if err != nil || len(list) != 249 || written != 250 || list[0].ID != "249" || list[248].ID != "000" {
+		t.Error("search", len(list), written, err)
+	}
+	if list, _ := db.Search("password", 10, func(*filesupport.PasteMeta) bool { return true }); len(list) != 10 || list[9].ID != "240" {
+		t.Error("limit", ids(list))
+	}
+}
+
+func TestAtRest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	defer func() { filesupport.Key = nil }()
+	filesupport.Key = []byte("0123456789abcdef0123456789abcdef")
+	store := filesupport.NewLocal(dir)
+	email := "alice.leak@example.com"
+	text := "login " + email + " password hunter2"
+	meta := &filesupport.PasteMeta{PasteJSON: filesupport.PasteJSON{Key: "AbCdEf12", User: "bobthebuilder"},
+		Match: "corp-password", Tags: []string{"creds-leak"}, Source: "pastebin",
+		Indicators: &indicators.Indicators{Emails: []string{email}}}
+	if saved, err := filesupport.Save(meta, text, store); err != nil || !saved {
+		t.Fatal("save", err)
+	}
+	db, err := findings.Open(filepath.Join(dir, filesupport.MetaDir, findings.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.IndexKey = filesupport.Key
+	if err := db.Put(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Index(meta, text); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing in the output folder discloses the indicators, the user, the rules or the key of the bin
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, _ := ioutil.ReadFile(path)
+		for _, secret := range []string{email, "alice", "bobthebuilder", "corp-password", "creds-leak", "AbCdEf12"} {
+			if strings.Contains(string(b), secret) {
+				t.Error("stored in clear:", path, secret)
+			}
+		}
+		return nil
+	})
+	for _, q := range []findings.Query{{User: "bobthebuilder"}, {Rule: "corp-password"}, {Tag: "creds-leak"}, {Source: "pastebin"}} {
+		if list, err := db.Query(q); err != nil || len(list) != 1 || list[0].ID != meta.ID {
+			t.Error("query", q, ids(list), err)
+		}
+	}
+	if got, err := db.Get(meta.ID); err != nil || got == nil || got.Indicators.Emails[0] != email {
+		t.Error("get", got, err)
+	}
+	if got, err := db.Search("'"+email+"'", 0, func(*filesupport.PasteMeta) bool { return true }); err != nil || len(got) != 1 {
+		t.Error("search", got, err)
+	}
+	if err := db.Delete(meta.ID); err != nil || db.Count() != 0 {
+		t.Error("delete", err)
+	}
+}
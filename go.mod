@@ -10,5 +10,7 @@ require (
 	github.com/jroimartin/gocui v0.4.0
 	github.com/nsf/termbox-go v1.1.1 // indirect
 	github.com/stretchr/testify v1.7.0 // indirect
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/text v0.3.6
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 )
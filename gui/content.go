@@ -0,0 +1,123 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/pegmatch"
+)
+
+// Content of a bin for the 'content' view: the header with its metadata, if any, and the text with
+// the terms of the matching rules highlighted
+func binContent(meta *filesupport.PasteMeta, text string) string {
+	if meta.FullURL == "" && len(meta.Matches) == 0 {
+		return text
+	}
+	var header []string
+	header = append(header, fmt.Sprintf("URL: %s  Key: %s  User: %s", meta.FullURL, meta.Key, meta.User))
+	header = append(header, fmt.Sprintf("Date: %s  Syntax: %s  Size: %s  Expire: %s", meta.Date, meta.Syntax, meta.Size, meta.Expire))
+	if meta.Source != "" {
+		header = append(header, fmt.Sprintf("Source: %s  Fetched: %s", meta.Source, meta.FetchedAt.Format("2006-01-02 15:04:05")))
+	}
+	if label := triageLabel(meta); label != "" {
+		header = append(header, "Triage:"+label)
+	}
+	var spans []pegmatch.Span
+	for _, m := range meta.Matches {
+		s := "Rule: " + m.Rule
+		if c := severityColors[m.Severity]; c != "" {
+			s = "Rule: " + c + m.Rule + "\x1b[0m"
+		}
+		if m.Severity != "" {
+			s += " [" + m.Severity + "]"
+		}
+		if m.Title {
+			s += " in the title"
+		}
+		if m.Layer != "" {
+			s += " in " + m.Layer
+		}
+		if len(m.Spans) > 0 {
+			s += fmt.Sprintf(", %d hits", len(m.Spans))
+		}
+		header = append(header, s)
+		// Only the spans of the original text can be highlighted
+		if !m.Title && m.Layer == "" && !m.Normalized {
+			spans = append(spans, m.Spans...)
+		}
+	}
+	return strings.Join(header, "\n") + "\n\n" + highlight(text, spans)
+}
+
+// Bin of the line under the cursor of the 'list' view
+func selected(v *gocui.View) *filesupport.PasteMeta {
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	if i := oy + cy; i >= 0 && i < len(entries) {
+		return entries[i]
+	}
+	return nil
+}
+
+// Highlight the spans of the text in reverse video
+func highlight(text string, spans []pegmatch.Span) string {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	var out strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.Start < last {
+			s.Start = last
+		}
+		if s.End > len(text) || s.Start >= s.End {
+			continue
+		}
+		out.WriteString(text[last:s.Start])
+		out.WriteString("\x1b[7m" + text[s.Start:s.End] + "\x1b[0m")
+		last = s.End
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// Label of the triage of a bin in the 'list' view
+func triageLabel(meta *filesupport.PasteMeta) string {
+	label := ""
+	switch meta.Triage {
+	case filesupport.TruePositive:
+		label += " [TP]"
+	case filesupport.FalsePositive:
+		label += " [FP]"
+	}
+	if meta.LegalHold {
+		label += " [HOLD]"
+	}
+	return label
+}
+
+// Set a triage state, or clear it if already set
+func toggle(current string, state string) string {
+	if current == state {
+		return ""
+	}
+	return state
+}
+
+// Change the triage of the selected bin and save it in its metadata and in the database
+func triage(g *gocui.Gui, v *gocui.View, change func(*filesupport.PasteMeta)) error {
+	meta := selected(v)
+	if meta == nil || DB == nil || meta.ID == "" {
+		return nil
+	}
+	change(meta)
+	if err := filesupport.SaveMeta(meta, Store); err != nil {
+		PrintTo("log", err.Error())
+	}
+	if err := DB.Put(meta); err != nil {
+		PrintTo("log", err.Error())
+	}
+	listDir(g)
+	return nil
+}
@@ -2,19 +2,37 @@ package gui
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
-	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
 
 	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/findings"
 	"github.com/jroimartin/gocui"
 )
 
-var BaseDir string
+// Store of the saved bins
+var Store filesupport.Store
+
 var MainGui *gocui.Gui
 
+// Saved bins, in the order of the lines of the 'list' view
+var entries []*filesupport.PasteMeta
+
+// Log file, shown in the title of the 'log' view
+var LogPath string
+
+// Database of the bins, the output folder is listed without it
+var DB *findings.DB
+
+// Colour of the bins in the 'list' view by the severity of the rule
+var severityColors = map[string]string{
+	"low":      "\x1b[32m",
+	"medium":   "\x1b[33m",
+	"high":     "\x1b[31m",
+	"critical": "\x1b[35;1m",
+}
+
 // Move the cursor of 'list' view and show the content of the highlighted bin
 func scrollView(g *gocui.Gui, v *gocui.View, dy int) error {
 	if v != nil {
@@ -28,12 +46,11 @@ func scrollView(g *gocui.Gui, v *gocui.View, dy int) error {
 
 				// Update the view
 				vc.Clear()
-				_, cy := vl.Cursor()
-				l, _ := vl.Line(cy)
-				l = filepath.Clean(BaseDir + string(filepath.Separator) + l)
-				if _, err := os.Stat(l); err == nil {
-					if b, err := ioutil.ReadFile(l); err == nil {
-						PrintTo("content", string(b))
+				if meta := selected(vl); meta != nil {
+					if text, err := filesupport.ReadBin(meta, Store); err == nil {
+						PrintTo("content", binContent(meta, text))
+					} else {
+						PrintTo("content", err.Error())
 					}
 				}
 				return nil
@@ -71,22 +88,39 @@ func moveTo(step int, v *gocui.View) {
 }
 
 func ListDir() {
-	listDir(MainGui, BaseDir)
+	// Nothing to refresh without the TUI, i.e. replay
+	if MainGui == nil {
+		return
+	}
+	listDir(MainGui)
 }
 
 //Get the list the of the saved files
-func listDir(g *gocui.Gui, dir string) {
+func listDir(g *gocui.Gui) {
 	g.Update(func(g *gocui.Gui) error {
 		v, _ := g.View("list")
 		v.Clear()
-		dir, _ = filepath.Abs(filepath.Clean(dir))
-		files, _ := ioutil.ReadDir(dir)
-		for _, f := range files {
-			if !f.IsDir() {
-				PrintTo("list", f.Name())
+		switch {
+		case searchQuery != "":
+			// Results of the search prompt, the most recent first
+			entries = searchResults
+		case DB != nil:
+			entries, _ = DB.Query(findings.Query{})
+			sort.SliceStable(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+		default:
+			entries, _ = filesupport.List(Store)
+		}
+		for _, e := range entries {
+			if severityColors[e.Severity] != "" {
+				PrintTo("list", severityColors[e.Severity]+e.Name+"\x1b[0m"+triageLabel(e))
+			} else {
+				PrintTo("list", e.Name+triageLabel(e))
 			}
 		}
-		v.Title = "Files: " + strconv.Itoa(len(files))
+		v.Title = "Files: " + strconv.Itoa(len(entries))
+		if searchQuery != "" {
+			v.Title = "Search: " + searchQuery + " - " + v.Title
+		}
 		scrollView(g, v, 0)
 		return nil
 	})
@@ -108,7 +142,7 @@ func layout(g *gocui.Gui) error {
 		v.FgColor = gocui.ColorCyan | gocui.AttrBold
 		v.SelBgColor = gocui.ColorCyan
 		v.SelFgColor = gocui.ColorYellow | gocui.AttrBold
-		listDir(g, BaseDir)
+		listDir(g)
 		scrollView(g, v, 0)
 	}
 	// Content view: display the selected file content
@@ -126,12 +160,18 @@ func layout(g *gocui.Gui) error {
 		}
 		v.Wrap = true
 		v.Title = "Log"
+		if LogPath != "" {
+			v.Title += ": " + LogPath
+		}
 		v.Autoscroll = true
 	}
 	return nil
 }
 
 func PrintTo(gui string, s string) {
+	if MainGui == nil {
+		return
+	}
 	v, e := MainGui.View(gui)
 	if e == nil {
 		fmt.Fprintln(v, s)
@@ -192,8 +232,14 @@ func initKeybindings(g *gocui.Gui) error {
 	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
 		return err
 	}
-	// quit
-	if err := g.SetKeybinding("", 'q', gocui.ModNone, quit); err != nil {
+	// quit, or type 'q' in the search prompt
+	if err := g.SetKeybinding("", 'q', gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		if v != nil && v.Editable {
+			v.EditWrite('q')
+			return nil
+		}
+		return quit(g, v)
+	}); err != nil {
 		return err
 	}
 	// move up
@@ -246,13 +292,23 @@ func initKeybindings(g *gocui.Gui) error {
 	// delete an entry/file
 	if err := g.SetKeybinding("list", 'd', gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
 		vl, _ := g.View("list")
-		_, cy := vl.Cursor()
-		l, _ := vl.Line(cy)
+		meta := selected(vl)
+		if meta == nil {
+			return nil
+		}
+		if meta.LegalHold {
+			PrintTo("log", meta.Name+" is on legal hold")
+			return nil
+		}
 
-		if err := filesupport.DeleteFile(l, BaseDir); err == nil {
+		if err := filesupport.Delete(meta, Store); err == nil {
+			if DB != nil {
+				DB.Delete(meta.ID)
+			}
+			forgetResult(meta)
 			// Update cursor state
 			g.Update(func(g *gocui.Gui) error {
-				listDir(g, BaseDir)
+				listDir(g)
 				_, sy := v.Size()
 				// Realign the view
 				if l, e := vl.Line(sy); len(l) <= 0 || e != nil {
@@ -266,12 +322,48 @@ func initKeybindings(g *gocui.Gui) error {
 	}); err != nil {
 		return err
 	}
+	// triage as true positive
+	if err := g.SetKeybinding("list", 't', gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		return triage(g, v, func(m *filesupport.PasteMeta) { m.Triage = toggle(m.Triage, filesupport.TruePositive) })
+	}); err != nil {
+		return err
+	}
+	// triage as false positive
+	if err := g.SetKeybinding("list", 'f', gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		return triage(g, v, func(m *filesupport.PasteMeta) { m.Triage = toggle(m.Triage, filesupport.FalsePositive) })
+	}); err != nil {
+		return err
+	}
+	// search prompt
+	if err := g.SetKeybinding("list", '/', gocui.ModNone, openSearch); err != nil {
+		return err
+	}
+	// clear the search
+	if err := g.SetKeybinding("list", gocui.KeyEsc, gocui.ModNone, clearSearch); err != nil {
+		return err
+	}
+	// refresh the results of the search
+	if err := g.SetKeybinding("list", 'r', gocui.ModNone, refreshSearch); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", gocui.KeyEnter, gocui.ModNone, runSearch); err != nil {
+		return err
+	}
+	if err := g.SetKeybinding("search", gocui.KeyEsc, gocui.ModNone, closeSearch); err != nil {
+		return err
+	}
+	// legal hold
+	if err := g.SetKeybinding("list", 'h', gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		return triage(g, v, func(m *filesupport.PasteMeta) { m.LegalHold = !m.LegalHold })
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
-func SetGui(outputTo string) {
+func SetGui(store filesupport.Store) {
 	MainGui, _ = gocui.NewGui(gocui.Output256)
-	BaseDir, _ = filepath.Abs(filepath.Clean(outputTo))
+	Store = store
 	defer MainGui.Close()
 	MainGui.SetManagerFunc(layout)
 	MainGui.Mouse = false
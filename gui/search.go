@@ -0,0 +1,107 @@
+package gui
+
+import (
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/notdodo/pastego/filesupport"
+)
+
+// Search the saved bins matching an expression, the most recent first: enables the search prompt
+var Search func(query string) ([]*filesupport.PasteMeta, error)
+
+// Query of the search shown in the 'list' view, empty to show all the bins
+var searchQuery string
+
+// Results of the search: refreshed only on a new query or with 'r', not when the bins change
+var searchResults []*filesupport.PasteMeta
+
+// Show the search prompt
+func openSearch(g *gocui.Gui, v *gocui.View) error {
+	if Search == nil {
+		return nil
+	}
+	maxX, maxY := g.Size()
+	if v, err := g.SetView("search", maxX/4-4, maxY/2-1, maxX-1, maxY/2+1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Search (enter: search, esc: cancel)"
+		v.Editable = true
+		v.Write([]byte(searchQuery))
+		v.SetCursor(len(searchQuery), 0)
+	}
+	g.Cursor = true
+	_, err := g.SetCurrentView("search")
+	return err
+}
+
+// Hide the search prompt
+func closeSearch(g *gocui.Gui, v *gocui.View) error {
+	g.Cursor = false
+	if err := g.DeleteView("search"); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView("list")
+	return err
+}
+
+// Search the expression of the prompt and show the results in the 'list' view
+func runSearch(g *gocui.Gui, v *gocui.View) error {
+	query := strings.TrimSpace(v.Buffer())
+	if err := closeSearch(g, v); err != nil {
+		return err
+	}
+	var results []*filesupport.PasteMeta
+	if query != "" {
+		var err error
+		if results, err = Search(query); err != nil {
+			PrintTo("log", "Search: "+err.Error())
+			return nil
+		}
+	}
+	return showSearch(g, query, results)
+}
+
+// Search again the query, showing the bins saved or changed after it
+func refreshSearch(g *gocui.Gui, v *gocui.View) error {
+	if searchQuery == "" {
+		return nil
+	}
+	results, err := Search(searchQuery)
+	if err != nil {
+		PrintTo("log", "Search: "+err.Error())
+		return nil
+	}
+	searchResults = results
+	listDir(g)
+	return nil
+}
+
+// Remove a deleted bin from the results of the search
+func forgetResult(meta *filesupport.PasteMeta) {
+	for i, m := range searchResults {
+		if m == meta {
+			searchResults = append(searchResults[:i:i], searchResults[i+1:]...)
+			return
+		}
+	}
+}
+
+// Show the results of a query, or all the bins with an empty query
+func showSearch(g *gocui.Gui, query string, results []*filesupport.PasteMeta) error {
+	searchQuery, searchResults = query, results
+	vl, _ := g.View("list")
+	vl.SetOrigin(0, 0)
+	vl.SetCursor(0, 0)
+	listDir(g)
+	return nil
+}
+
+// Clear the search, showing all the bins
+func clearSearch(g *gocui.Gui, v *gocui.View) error {
+	if searchQuery == "" {
+		return nil
+	}
+	return showSearch(g, "", nil)
+}
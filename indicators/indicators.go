@@ -0,0 +1,205 @@
+package indicators
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Indicators of compromise found inside a bin
+type Indicators struct {
+	Emails  []string `json:"emails,omitempty"`
+	Domains []string `json:"domains,omitempty"`
+	IPv4    []string `json:"ipv4,omitempty"`
+	IPv6    []string `json:"ipv6,omitempty"`
+	URLs    []string `json:"urls,omitempty"`
+	MD5     []string `json:"md5,omitempty"`
+	SHA1    []string `json:"sha1,omitempty"`
+	SHA256  []string `json:"sha256,omitempty"`
+	Bitcoin []string `json:"bitcoin,omitempty"`
+}
+
+var (
+	reURL     = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s"'<>()\[\]{}]+`)
+	reEmail   = regexp.MustCompile(`(?i)\b[a-z0-9._%+\-]+@(?:[a-z0-9](?:[a-z0-9\-]{0,61}[a-z0-9])?\.)+[a-z]{2,24}\b`)
+	reDomain  = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9\-]{0,61}[a-z0-9])?\.)+[a-z]{2,24}\b`)
+	reIPv4    = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\b`)
+	reIPv6    = regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`)
+	reMD5     = regexp.MustCompile(`\b[A-Fa-f0-9]{32}\b`)
+	reSHA1    = regexp.MustCompile(`\b[A-Fa-f0-9]{40}\b`)
+	reSHA256  = regexp.MustCompile(`\b[A-Fa-f0-9]{64}\b`)
+	reBitcoin = regexp.MustCompile(`\b(?:bc1[ac-hj-np-z02-9]{25,87}|[13][1-9A-HJ-NP-Za-km-z]{25,34})\b`)
+)
+
+// Common file extensions that look like top level domains, i.e. 'config.php'
+var notTLD = map[string]bool{
+	"bak": true, "bat": true, "bin": true, "cfg": true, "conf": true, "cpp": true,
+	"css": true, "csv": true, "dat": true, "dll": true, "doc": true, "docx": true,
+	"exe": true, "gif": true, "go": true, "gz": true, "h": true, "htm": true,
+	"html": true, "ini": true, "java": true, "jpg": true, "js": true, "json": true,
+	"jsp": true, "log": true, "md": true, "php": true, "pl": true, "png": true,
+	"py": true, "rb": true, "sh": true, "sql": true, "swf": true, "tar": true,
+	"tmp": true, "txt": true, "xml": true, "yaml": true, "yml": true, "zip": true,
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Collect unique values keeping the order of appearance
+type set struct {
+	seen   map[string]bool
+	values []string
+}
+
+func (s *set) add(v string) {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	if !s.seen[v] {
+		s.seen[v] = true
+		s.values = append(s.values, v)
+	}
+}
+
+// Extract every indicator from a text, values are returned as found (not defanged)
+func Extract(text string) *Indicators {
+	var emails, domains, ipv4, ipv6, urls, md5, sha1, sha256, btc set
+
+	addDomain := func(d string) {
+		d = strings.ToLower(strings.TrimSuffix(d, "."))
+		if i := strings.LastIndex(d, "."); i > 0 && !notTLD[d[i+1:]] && net.ParseIP(d) == nil {
+			domains.add(d)
+		}
+	}
+
+	for _, u := range reURL.FindAllString(text, -1) {
+		u = strings.TrimRight(u, ".,;:!?'\"")
+		urls.add(u)
+		host := u[strings.Index(u, "://")+3:]
+		if i := strings.IndexAny(host, "/?#"); i >= 0 {
+			host = host[:i]
+		}
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		addDomain(host)
+	}
+	for _, e := range reEmail.FindAllString(text, -1) {
+		e = strings.ToLower(e)
+		emails.add(e)
+		addDomain(e[strings.LastIndex(e, "@")+1:])
+	}
+	for _, d := range reDomain.FindAllString(text, -1) {
+		addDomain(d)
+	}
+	for _, ip := range reIPv4.FindAllString(text, -1) {
+		ipv4.add(ip)
+	}
+	for _, ip := range reIPv6.FindAllString(text, -1) {
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil && strings.Trim(ip, ":") != "" {
+			ipv6.add(strings.ToLower(ip))
+		}
+	}
+	for _, h := range reMD5.FindAllString(text, -1) {
+		md5.add(strings.ToLower(h))
+	}
+	for _, h := range reSHA1.FindAllString(text, -1) {
+		sha1.add(strings.ToLower(h))
+	}
+	for _, h := range reSHA256.FindAllString(text, -1) {
+		sha256.add(strings.ToLower(h))
+	}
+	for _, a := range reBitcoin.FindAllString(text, -1) {
+		if strings.HasPrefix(a, "bc1") || validBase58Check(a) {
+			btc.add(a)
+		}
+	}
+
+	return &Indicators{
+		Emails:  emails.values,
+		Domains: domains.values,
+		IPv4:    ipv4.values,
+		IPv6:    ipv6.values,
+		URLs:    urls.values,
+		MD5:     md5.values,
+		SHA1:    sha1.values,
+		SHA256:  sha256.values,
+		Bitcoin: btc.values,
+	}
+}
+
+// Verify the checksum of a legacy (P2PKH/P2SH) bitcoin address
+func validBase58Check(a string) bool {
+	n := new(big.Int)
+	for _, r := range a {
+		i := strings.IndexRune(base58Alphabet, r)
+		if i < 0 {
+			return false
+		}
+		n.Mul(n, big.NewInt(58))
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	b := n.Bytes()
+	// Leading '1' are leading zero bytes
+	for _, r := range a {
+		if r != '1' {
+			break
+		}
+		b = append([]byte{0}, b...)
+	}
+	if len(b) != 25 {
+		return false
+	}
+	first := sha256.Sum256(b[:21])
+	second := sha256.Sum256(first[:])
+	return string(second[:4]) == string(b[21:])
+}
+
+// Empty returns true when nothing has been found
+func (i *Indicators) Empty() bool {
+	return len(i.Emails)+len(i.Domains)+len(i.IPv4)+len(i.IPv6)+len(i.URLs)+
+		len(i.MD5)+len(i.SHA1)+len(i.SHA256)+len(i.Bitcoin) == 0
+}
+
+// Defang returns a copy of the indicators safe to be shared: no clickable links or addresses
+func (i *Indicators) Defang() *Indicators {
+	apply := func(values []string, f func(string) string) []string {
+		var out []string
+		for _, v := range values {
+			out = append(out, f(v))
+		}
+		return out
+	}
+	return &Indicators{
+		Emails:  apply(i.Emails, Defang),
+		Domains: apply(i.Domains, Defang),
+		IPv4:    apply(i.IPv4, Defang),
+		IPv6:    apply(i.IPv6, func(s string) string { return strings.Replace(s, ":", "[:]", -1) }),
+		URLs:    apply(i.URLs, Defang),
+		MD5:     i.MD5,
+		SHA1:    i.SHA1,
+		SHA256:  i.SHA256,
+		Bitcoin: i.Bitcoin,
+	}
+}
+
+// Defang an URL, email, domain or IPv4: 'http://evil.com' becomes 'hxxp[://]evil[.]com'
+func Defang(s string) string {
+	if i := strings.Index(s, "://"); i >= 0 {
+		scheme := strings.Replace(strings.Replace(s[:i], "t", "x", -1), "T", "X", -1)
+		s = scheme + "[://]" + s[i+3:]
+	}
+	s = strings.Replace(s, ".", "[.]", -1)
+	return strings.Replace(s, "@", "[@]", -1)
+}
+
+// Refang reverts Defang
+func Refang(s string) string {
+	r := strings.NewReplacer("[.]", ".", "[@]", "@", "[:]", ":", "hxxps[://]", "https://",
+		"hxxp[://]", "http://", "fxp[://]", "ftp://", "[://]", "://")
+	return r.Replace(s)
+}
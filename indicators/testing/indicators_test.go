@@ -0,0 +1,52 @@
+package indicators_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/notdodo/pastego/indicators"
+)
+
+func TestExtract(t *testing.T) {
+	text := `
+		admin@corp.com:hunter2
+		visit http://evil.example.org/login.php?id=1, or 10.0.0.1 / fe80::1
+		config.php d41d8cd98f00b204e9800998ecf8427e
+		donate 1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2 or 1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3`
+	got := indicators.Extract(text)
+	if !reflect.DeepEqual(got.Emails, []string{"admin@corp.com"}) {
+		t.Error("emails", got.Emails)
+	}
+	if !reflect.DeepEqual(got.Domains, []string{"evil.example.org", "corp.com"}) {
+		t.Error("domains", got.Domains)
+	}
+	if !reflect.DeepEqual(got.URLs, []string{"http://evil.example.org/login.php?id=1"}) {
+		t.Error("urls", got.URLs)
+	}
+	if !reflect.DeepEqual(got.IPv4, []string{"10.0.0.1"}) || !reflect.DeepEqual(got.IPv6, []string{"fe80::1"}) {
+		t.Error("ips", got.IPv4, got.IPv6)
+	}
+	if len(got.MD5) != 1 || len(got.SHA1) != 0 || len(got.SHA256) != 0 {
+		t.Error("hashes", got.MD5, got.SHA1, got.SHA256)
+	}
+	// The second address has an invalid checksum
+	if !reflect.DeepEqual(got.Bitcoin, []string{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}) {
+		t.Error("bitcoin", got.Bitcoin)
+	}
+}
+
+func TestDefang(t *testing.T) {
+	cases := map[string]string{
+		"http://evil.com/a.php": "hxxp[://]evil[.]com/a[.]php",
+		"admin@corp.com":        "admin[@]corp[.]com",
+		"10.0.0.1":              "10[.]0[.]0[.]1",
+	}
+	for in, out := range cases {
+		if got := indicators.Defang(in); got != out {
+			t.Errorf("Defang(%q) = %q", in, got)
+		}
+		if got := indicators.Refang(out); got != in {
+			t.Errorf("Refang(%q) = %q", out, got)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level of a log line
+type Level int
+
+// Levels, from the most verbose
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses the name of a level: debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return Info, fmt.Errorf("invalid log level %q", s)
+}
+
+// Logger writes levelled lines, as text or JSON, to a file rotated by size and age.
+// The zero values disable the rotation; the methods are safe for concurrent use
+type Logger struct {
+	Path  string
+	Level Level
+	// JSON lines instead of text
+	JSON bool
+	// Rotate the file when bigger, in bytes
+	MaxSize int64
+	// Rotate the file when opened for longer
+	MaxAge time.Duration
+	// Number of rotated files kept: 'file.1' is the most recent
+	Backups int
+	// Called on the first failed write after a successful one, i.e. to show it in the TUI
+	OnError func(error)
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	failed bool
+}
+
+// Open the log file, creating its folder
+func (l *Logger) Open() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.open()
+}
+
+func (l *Logger) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size, l.opened = f, info.Size(), time.Now()
+	return nil
+}
+
+// Close the log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Debug logs a message with its fields: key and value pairs
+func (l *Logger) Debug(msg string, fields ...interface{}) {
+	l.Log(Debug, msg, fields...)
+}
+
+// Info logs a message with its fields: key and value pairs
+func (l *Logger) Info(msg string, fields ...interface{}) {
+	l.Log(Info, msg, fields...)
+}
+
+// Warn logs a message with its fields: key and value pairs
+func (l *Logger) Warn(msg string, fields ...interface{}) {
+	l.Log(Warn, msg, fields...)
+}
+
+// Error logs a message with its fields: key and value pairs
+func (l *Logger) Error(msg string, fields ...interface{}) {
+	l.Log(Error, msg, fields...)
+}
+
+// Log writes a line when the level is enabled, rotating the file first if needed
+func (l *Logger) Log(level Level, msg string, fields ...interface{}) {
+	if l == nil || level < l.Level {
+		return
+	}
+	line := l.format(time.Now(), level, msg, fields)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.rotate(int64(len(line)))
+	if err == nil && l.file == nil {
+		err = l.open()
+	}
+	if err == nil {
+		var n int
+		n, err = l.file.Write(line)
+		l.size += int64(n)
+	}
+	if err != nil && !l.failed && l.OnError != nil {
+		l.OnError(err)
+	}
+	l.failed = err != nil
+}
+
+// Rotate the file when the next write would exceed MaxSize or when it is older than MaxAge
+func (l *Logger) rotate(next int64) error {
+	if l.file == nil || l.size == 0 {
+		return nil
+	}
+	if !(l.MaxSize > 0 && l.size+next > l.MaxSize) && !(l.MaxAge > 0 && time.Since(l.opened) > l.MaxAge) {
+		return nil
+	}
+	l.file.Close()
+	l.file = nil
+	if l.Backups <= 0 {
+		return os.Remove(l.Path)
+	}
+	os.Remove(l.Path + "." + strconv.Itoa(l.Backups))
+	for i := l.Backups - 1; i > 0; i-- {
+		os.Rename(l.Path+"."+strconv.Itoa(i), l.Path+"."+strconv.Itoa(i+1))
+	}
+	return os.Rename(l.Path, l.Path+".1")
+}
+
+// Line of a message: 'time LEVEL message key=value ...' or a JSON object
+func (l *Logger) format(t time.Time, level Level, msg string, fields []interface{}) []byte {
+	if len(fields)%2 != 0 {
+		fields = append(fields, "")
+	}
+	if l.JSON {
+		m := map[string]interface{}{"time": t.Format(time.RFC3339), "level": level.String(), "msg": msg}
+		for i := 0; i < len(fields); i += 2 {
+			v := fields[i+1]
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			m[fmt.Sprint(fields[i])] = v
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			b, _ = json.Marshal(map[string]string{"time": t.Format(time.RFC3339), "level": level.String(), "msg": msg})
+		}
+		return append(b, '\n')
+	}
+	var b strings.Builder
+	b.WriteString(t.Format(time.RFC3339) + " " + strings.ToUpper(level.String()) + " " + msg)
+	for i := 0; i < len(fields); i += 2 {
+		b.WriteString(" " + fmt.Sprint(fields[i]) + "=" + quote(fmt.Sprint(fields[i+1])))
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// Quote a value with spaces, quotes or control characters
+func quote(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return r <= ' ' || r == '"' || r == '=' }) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
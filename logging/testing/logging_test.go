@@ -0,0 +1,93 @@
+package logging_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/notdodo/pastego/logging"
+)
+
+func TestLevels(t *testing.T) {
+	for _, name := range []string{"debug", "INFO", "Warn", "error"} {
+		if l, err := logging.ParseLevel(name); err != nil || l.String() != strings.ToLower(name) {
+			t.Error(name, l, err)
+		}
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Error("invalid level parsed")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+
+	text := &logging.Logger{Path: filepath.Join(dir, "logs", "text.log"), Level: logging.Info}
+	text.Debug("hidden")
+	text.Info("Suppressed", "rule", "password", "url", "https://pastebin.com/x y")
+	text.Error("Failed", "err", errors.New("disk full"))
+	text.Close()
+	b, err := ioutil.ReadFile(text.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	want := []string{
+		`^\S+ INFO Suppressed rule=password url="https://pastebin.com/x y"$`,
+		`^\S+ ERROR Failed err="disk full"$`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("lines %q", lines)
+	}
+	for i, re := range want {
+		if !regexp.MustCompile(re).MatchString(lines[i]) {
+			t.Errorf("line %q, want %s", lines[i], re)
+		}
+	}
+
+	js := &logging.Logger{Path: filepath.Join(dir, "json.log"), JSON: true}
+	js.Warn("Storage unavailable", "pending", 3, "err", errors.New("timeout"))
+	js.Close()
+	b, _ = ioutil.ReadFile(js.Path)
+	var line map[string]interface{}
+	if err := json.Unmarshal(b, &line); err != nil {
+		t.Fatal(err, string(b))
+	}
+	if line["level"] != "warn" || line["msg"] != "Storage unavailable" || line["pending"] != 3.0 || line["err"] != "timeout" || line["time"] == "" {
+		t.Error("json", line)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	l := &logging.Logger{Path: filepath.Join(dir, "pastego.log"), MaxSize: 100, Backups: 2}
+	defer l.Close()
+	for i := 0; i < 10; i++ {
+		l.Info(strings.Repeat("x", 40))
+	}
+	files, _ := filepath.Glob(l.Path + "*")
+	if len(files) != 3 {
+		t.Fatal("files", files)
+	}
+	for _, f := range files {
+		if info, _ := os.Stat(f); info.Size() > 100 {
+			t.Error("not rotated", f, info.Size())
+		}
+	}
+
+	// Failures reported once
+	errs := 0
+	bad := &logging.Logger{Path: filepath.Join(l.Path, "not-a-folder", "x.log"), OnError: func(error) { errs++ }}
+	bad.Info("a")
+	bad.Info("b")
+	if errs != 1 {
+		t.Error("errors reported", errs)
+	}
+}
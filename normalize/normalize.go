@@ -0,0 +1,79 @@
+package normalize
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Steps available, in the order they are applied
+var Steps = []string{"entities", "nfkc", "zerowidth", "confusables", "whitespace"}
+
+// Normalizer rewrites the content of a bin before matching
+type Normalizer struct {
+	steps map[string]bool
+}
+
+// Invisible characters used to split words
+var zeroWidth = strings.NewReplacer(
+	"\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "", "\u00ad", "", "\u180e", "",
+)
+
+// Characters looking like latin letters, mostly Cyrillic and Greek
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't',
+	'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ј': 'j', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ɡ': 'g', 'ɩ': 'i',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T',
+	'У': 'Y', 'Х': 'X', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J',
+	'α': 'a', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O',
+	'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// New creates a Normalizer applying the given steps, see Steps
+func New(steps []string) (*Normalizer, error) {
+	n := &Normalizer{steps: map[string]bool{}}
+	for _, s := range steps {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		known := false
+		for _, k := range Steps {
+			known = known || k == s
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown normalization step %q, available: %s", s, strings.Join(Steps, ","))
+		}
+		n.steps[s] = true
+	}
+	return n, nil
+}
+
+// Normalize returns the normalized text, the original one is untouched
+func (n *Normalizer) Normalize(s string) string {
+	if n.steps["entities"] {
+		s = html.UnescapeString(s)
+	}
+	if n.steps["nfkc"] {
+		s = norm.NFKC.String(s)
+	}
+	if n.steps["zerowidth"] {
+		s = zeroWidth.Replace(s)
+	}
+	if n.steps["confusables"] {
+		s = strings.Map(func(r rune) rune {
+			if c, ok := confusables[r]; ok {
+				return c
+			}
+			return r
+		}, s)
+	}
+	if n.steps["whitespace"] {
+		s = strings.Join(strings.FieldsFunc(s, unicode.IsSpace), " ")
+	}
+	return s
+}
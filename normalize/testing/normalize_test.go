@@ -0,0 +1,49 @@
+package normalize_test
+
+import (
+	"testing"
+
+	"github.com/notdodo/pastego/normalize"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		steps []string
+		in    string
+		want  string
+	}{
+		{[]string{"entities"}, "p&#97;ss&amp;w&#x6f;rd", "pass&word"},
+		{[]string{"nfkc"}, "ｐａｓｓｗｏｒｄ ﬁle", "password file"},
+		{[]string{"zerowidth"}, "pa\u200bss\u200dwo\u00adrd\ufeff", "password"},
+		{[]string{"confusables"}, "раѕѕwοrd", "password"},
+		{[]string{"whitespace"}, " pass \t\n  word  ", "pass word"},
+		// Only the given steps are applied
+		{[]string{"nfkc"}, "p&#97;ss", "p&#97;ss"},
+		{[]string{"confusables"}, "pa\u200bss", "pa\u200bss"},
+		{nil, "ｐａｓｓ", "ｐａｓｓ"},
+		// The entities are decoded before the other steps
+		{normalize.Steps, "&#65360;&#1072;s&#8203;s \n word", "pass word"},
+	}
+	for _, c := range cases {
+		n, err := normalize.New(c.steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.Normalize(c.in); got != c.want {
+			t.Errorf("%v %q: got %q, want %q", c.steps, c.in, got, c.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := normalize.New([]string{"nfkc", "rot13"}); err == nil {
+		t.Error("unknown step accepted")
+	}
+	n, err := normalize.New([]string{" NFKC ", "", "Whitespace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.Normalize("ｐａｓｓ  word"); got != "pass word" {
+		t.Error("steps", got)
+	}
+}
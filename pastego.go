@@ -2,18 +2,30 @@ package main
 
 import (
 	// import standard libraries
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/notdodo/pastego/combolist"
+	"github.com/notdodo/pastego/decoder"
 	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/findings"
 	"github.com/notdodo/pastego/gui"
+	"github.com/notdodo/pastego/indicators"
+	"github.com/notdodo/pastego/logging"
+	"github.com/notdodo/pastego/normalize"
 	"github.com/notdodo/pastego/pegmatch"
+	"github.com/notdodo/pastego/rules"
+	"github.com/notdodo/pastego/suppress"
+	"github.com/notdodo/pastego/watchlist"
 
 	// import third party libraries
 	"github.com/PuerkitoBio/goquery"
@@ -22,69 +34,285 @@ import (
 
 // Command line args
 var (
-	searchFor  = kingpin.Flag("search", "Strings to search with optional bool operator(&&, ||, ~), i.e: \"password,some || (thing && ~maybenot), \"").Short('s').Default("pass").String()
-	outputTo   = kingpin.Flag("output", "Folder to save the bins. Default : './results'").Short('o').Default("results").String()
-	caseInsens = kingpin.Flag("insensitive", "Search for case-insensitive strings").Default("false").Short('i').Bool()
+	searchFor   = kingpin.Flag("search", "Strings to search with optional bool operator(&&, ||, ~), i.e: \"password,some || (thing && ~maybenot), \". Default: 'pass' without --rules").Short('s').String()
+	rulesFile   = kingpin.Flag("rules", "JSON file with the named rules to search").Short('r').ExistingFile()
+	macroDefs   = kingpin.Flag("macro", "Macro usable in the expressions as '$name', i.e: \"$noise = php || sudo || Linux\"").Short('m').Strings()
+	suppressTo  = kingpin.Flag("suppress", "JSON file with the suppressions of the known-noisy bins").ExistingFile()
+	statsTo     = kingpin.Flag("suppress-stats", "File of the stats of the suppressions. Default: '<output>/.meta/suppressions-stats.json'").String()
+	outputTo    = kingpin.Flag("output", "Folder to save the bins. Default : './results'").Short('o').Default("results").String()
+	storeURL    = kingpin.Flag("store", "Save the bins in an S3-compatible bucket instead of the output folder: s3://bucket/prefix").String()
+	s3Endpoint  = kingpin.Flag("s3-endpoint", "URL of the S3-compatible service, i.e. 'http://localhost:9000'. Default: AWS").String()
+	s3Region    = kingpin.Flag("s3-region", "Region of the bucket").Default("us-east-1").String()
+	caseInsens  = kingpin.Flag("insensitive", "Search for case-insensitive strings").Default("false").Short('i').Bool()
+	decodeDepth = kingpin.Flag("decode-depth", "Decode base64/hex/URL-encoded/gzip/zlib blobs up to this depth before searching, 0 to disable").Default("2").Int()
+	decodeSize  = kingpin.Flag("decode-max-size", "Maximum amount of bytes decoded from a single bin").Default("1048576").Int()
+	compress    = kingpin.Flag("compress", "Compress the saved bins with gzip").Bool()
+	keyFile     = kingpin.Flag("key-file", "File with the AES-256 key (32 bytes, raw, hex or base64) to encrypt the saved bins").ExistingFile()
+	maxAge      = kingpin.Flag("max-age", "Delete the bins older than this age, i.e. '90d', '2w', '36h'").String()
+	maxSize     = kingpin.Flag("max-size", "Delete the oldest bins while the saved bins are bigger than this size, i.e. '10GB'").Bytes()
+	severityAge = kingpin.Flag("severity-max-age", "Age of the bins of a severity overriding --max-age, '0' to keep them forever, i.e. 'critical=365d'").StringMap()
+	keepTrue    = kingpin.Flag("keep-true-positives", "Never delete the bins triaged as true positive").Default("true").Bool()
+	logPath     = kingpin.Flag("log-file", "Log file. Default: '<output>/.meta/pastego.log'").String()
+	logLevel    = kingpin.Flag("log-level", "Minimum level of the logged events: debug, info, warn or error").Default("info").Enum("debug", "info", "warn", "error")
+	logFormat   = kingpin.Flag("log-format", "Format of the log lines: text or json").Default("text").Enum("text", "json")
+	logMaxSize  = kingpin.Flag("log-max-size", "Rotate the log file when bigger than this size, 0 to disable").Default("10MB").Bytes()
+	logMaxAge   = kingpin.Flag("log-max-age", "Rotate the log file when opened for longer than this duration, 0 to disable").Default("24h").Duration()
+	logBackups  = kingpin.Flag("log-backups", "Number of rotated log files kept").Default("5").Int()
+	normSteps   = kingpin.Flag("normalize", "Normalization applied to the bins for the rules with 'normalize' or the expressions starting with 'norm:'").Default(strings.Join(normalize.Steps, ",")).String()
 )
 
-// Using PEG check if the bin contains the searched word/s
-func contains(link string, matches []string) (bool, string) {
-	var origMtch = make([]string, len(matches))
-	copy(origMtch, matches)
-	if *caseInsens {
-		link = strings.ToUpper(link)
-		for i, v := range matches {
-			matches[i] = strings.ToUpper(v)
-		}
-	}
-	pegmatch.PasteContentString = link
-	for i, mtch := range matches {
-		mtch = strings.TrimSpace(mtch)
-		got, err := pegmatch.ParseReader("", bytes.NewBufferString(mtch))
-		if err == nil && got.(bool) {
-			return true, strings.Split(origMtch[i], " ")[0]
-		}
-	}
-	return false, ""
+var matcher *rules.Matcher
+
+// Store of the saved bins: the output folder or a bucket
+var store filesupport.Store
+var suppressions *suppress.List
+
+// Log file
+var logger *logging.Logger
+
+// Index of the saved bins
+var findingsDB *findings.DB
+
+// Running without the TUI: the reports are printed on stdout
+var headless bool
+
+// Hot reload of the rules file
+var (
+	// Rules from the search expressions, kept on reload
+	searchRules []*rules.Rule
+	// Macros from the command line
+	macros = rules.Macros{}
+	// Modification time of the loaded rules file
+	rulesModTime time.Time
+	// Set by SIGHUP to force a reload
+	reloadRequested int32
+)
+
+// Functions available to the expressions
+func init() {
+	pegmatch.RegisterFunc("domain", watchlist.Domains)
+	pegmatch.RegisterFunc("ip", watchlist.IPs)
+	pegmatch.RegisterFunc("combos", combolist.Combos)
 }
 
 // Parse the page and read the content of the bin
 func pasteSearcher(link *filesupport.PasteJSON) {
+	text, err := fetchBin(link)
+	if err != nil {
+		logger.Warn("Bin not fetched", "url", link.ScrapeURL, "err", err)
+		return
+	}
+	processBin(link, text, "pastebin")
+}
+
+// Download the page of a bin and return its content
+func fetchBin(link *filesupport.PasteJSON) (string, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	response, err := client.Get(link.ScrapeURL)
 	if err != nil {
-		logToFile(err.Error())
-		return
+		return "", err
 	}
 	defer response.Body.Close()
 
 	doc, err := goquery.NewDocumentFromReader(response.Body)
 	if err != nil {
-		log.Fatalln(err)
-	}
-	doc.Find("body").Each(func(index int, item *goquery.Selection) {
-		bodyResult, bodyMatch := contains(item.Text(), strings.Split(*searchFor, ","))
-		titleResult, titleMatch := contains(link.Title, strings.Split(*searchFor, ","))
-		match := bodyMatch
-		if bodyResult || titleResult {
-			if titleResult {
-				match = titleMatch
-			}
-			if filesupport.SaveToFile(link, item.Text(), match, *outputTo) {
-				var s string
-				if link.Title != "" {
-					s = fmt.Sprintf("%s - %s - %s", match, link.FullURL, link.Title)
-				} else {
-					s = fmt.Sprintf("%s - %s", match, link.FullURL)
-				}
-				// Show recent pastes
-				gui.PrintTo("log", s)
-				logToFile(s)
-				// Triggers a reload
-				gui.ListDir()
+		return "", err
+	}
+	return doc.Find("body").Text(), nil
+}
+
+// Match the content of a bin against the rules, save it and report it: returns true if the bin is saved
+func processBin(link *filesupport.PasteJSON, text string, source string) bool {
+	// Decode the bin once for all the rules
+	layers, titleLayers := matcher.Layers(text), matcher.Layers(link.Title)
+	rule, layer := matcher.MatchLayers(layers)
+	if titleRule, _ := matcher.MatchLayers(titleLayers); titleRule != nil {
+		rule, layer = titleRule, ""
+	}
+	if rule == nil {
+		return false
+	}
+	if e := suppressions.Match(link, layers, matcher); e != nil {
+		logger.Info("Suppressed", "suppression", e.Name, "rule", rule.Name, "url", link.FullURL)
+		suppressed++
+		return false
+	}
+	// Replayed bins are dated by the paste: the backfilled data is filtered and retained by its own dates
+	fetchedAt := time.Now()
+	if date := binDate(link); strings.HasPrefix(source, "replay:") && !date.IsZero() {
+		fetchedAt = date
+	}
+	meta := &filesupport.PasteMeta{
+		PasteJSON: *link,
+		Match:     rule.Name,
+		Severity:  rule.Severity,
+		Tags:      rule.Tags,
+		Layer:     layer,
+		Source:    source,
+		FetchedAt: fetchedAt,
+	}
+	if filesupport.Exists(meta, text, store) {
+		return false
+	}
+	// Extract the indicators and store them in the metadata of the bin
+	meta.Matches = ruleMatches(link, layers, titleLayers)
+	meta.Indicators = indicators.Extract(text).Defang()
+	if combos := combolist.Parse(text); combos.Total > 0 {
+		meta.Combos = combos
+	}
+	saved, err := filesupport.Save(meta, text, store)
+	if err != nil {
+		// Keep alerting while the storage is unavailable
+		if !storageDown(meta, text, err) {
+			return false
+		}
+	} else if !saved {
+		return false
+	} else if findingsDB != nil {
+		indexSaved(meta, text, layers)
+	}
+	var s string
+	if link.Title != "" {
+		s = fmt.Sprintf("%s - %s - %s", rule.Name, link.FullURL, link.Title)
+	} else {
+		s = fmt.Sprintf("%s - %s", rule.Name, link.FullURL)
+	}
+	if layer != "" {
+		s += " (" + layer + ")"
+	}
+	if len(rule.Tags) > 0 {
+		s += " [" + strings.Join(rule.Tags, ",") + "]"
+	}
+	if err != nil {
+		s += " (not saved)"
+	}
+	// Show recent pastes
+	report(logging.Info, s, "rule", rule.Name, "url", link.FullURL, "id", meta.ID, "saved", err == nil)
+	// Triggers a reload
+	gui.ListDir()
+	return err == nil
+}
+
+// Maximum number of bins kept in memory while the storage is unavailable
+const maxPending = 1000
+
+// Bytes of an invalid response of the scraping API written to the log
+const maxLoggedBody = 512
+
+// Degraded mode: the bins not saved while the storage is unavailable, saved at the next cycles.
+// Only used by the goroutine of run() or by replay
+var (
+	pending    []*pendingBin
+	pendingIDs = map[string]bool{}
+)
+
+type pendingBin struct {
+	meta *filesupport.PasteMeta
+	text string
+}
+
+// Keep a bin not saved for an error of the storage, entering the degraded mode: returns false
+// if the bin is already pending
+func storageDown(meta *filesupport.PasteMeta, text string, err error) bool {
+	if pendingIDs[meta.ID] {
+		return false
+	}
+	if len(pending) == 0 {
+		report(logging.Error, "Storage unavailable, the bins are kept in memory until it is back: "+err.Error())
+	}
+	if len(pending) >= maxPending {
+		logger.Error("Too many bins not saved, dropping the bin", "id", meta.ID, "url", meta.FullURL)
+		return true
+	}
+	pending = append(pending, &pendingBin{meta, text})
+	pendingIDs[meta.ID] = true
+	return true
+}
+
+// Save the pending bins, leaving the degraded mode when all of them are saved: returns the number of bins saved
+func savePending() int {
+	n := 0
+	for len(pending) > 0 {
+		p := pending[0]
+		saved, err := filesupport.Save(p.meta, p.text, store)
+		if err != nil {
+			logger.Warn("Storage still unavailable", "pending", len(pending), "err", err)
+			return n
+		}
+		if saved {
+			n++
+			if findingsDB != nil {
+				indexSaved(p.meta, p.text, matcher.Layers(p.text))
 			}
 		}
-	})
+		pending = pending[1:]
+		delete(pendingIDs, p.meta.ID)
+		if len(pending) == 0 {
+			report(logging.Info, fmt.Sprintf("Storage available again: %d pending bins saved", n))
+			gui.ListDir()
+		}
+	}
+	return n
+}
+
+// Bins saved but not indexed in the findings database, i.e. locked by another process for too long:
+// indexed at the next cycles. Only used by the goroutine of run() or by replay
+var unindexed []*pendingBin
+
+// Index a saved bin, keeping it for the next cycles when the findings database is unavailable
+func indexSaved(meta *filesupport.PasteMeta, text string, layers []decoder.Layer) {
+	err := indexBin(meta, layers)
+	if err == nil {
+		return
+	}
+	if len(unindexed) >= maxPending {
+		report(logging.Error, "Too many bins not indexed, dropping the bin: "+err.Error(), "id", meta.ID)
+		return
+	}
+	report(logging.Warn, "Bin not indexed, retrying at the next cycle: "+err.Error(), "id", meta.ID)
+	unindexed = append(unindexed, &pendingBin{meta, text})
+}
+
+// Index the bins not indexed by the previous cycles: returns the number of bins indexed
+func indexPending() int {
+	n := 0
+	for len(unindexed) > 0 {
+		p := unindexed[0]
+		if err := indexBin(p.meta, matcher.Layers(p.text)); err != nil {
+			logger.Warn("Findings database still unavailable", "pending", len(unindexed), "err", err)
+			return n
+		}
+		n++
+		unindexed = unindexed[1:]
+	}
+	if n > 0 {
+		gui.ListDir()
+	}
+	return n
+}
+
+// Every rule matching the content or the title of a bin, with the spans of the terms: the layers are
+// decoded by Matcher.Layers
+func ruleMatches(link *filesupport.PasteJSON, layers []decoder.Layer, titleLayers []decoder.Layer) []filesupport.RuleMatch {
+	var out []filesupport.RuleMatch
+	add := func(hits []rules.Hit, title bool) {
+		for _, h := range hits {
+			out = append(out, filesupport.RuleMatch{
+				Rule:       h.Rule.Name,
+				Severity:   h.Rule.Severity,
+				Tags:       h.Rule.Tags,
+				Layer:      h.Layer,
+				Normalized: h.Rule.Normalize && matcher.Normalizer != nil,
+				Title:      title,
+				Spans:      h.Spans,
+			})
+		}
+	}
+	add(matcher.MatchAllLayers(layers), false)
+	if link.Title != "" {
+		add(matcher.MatchAllLayers(titleLayers), true)
+	}
+	return out
 }
 
 // Fetch the bins
@@ -96,7 +324,7 @@ func getBins(bins int) []filesupport.PasteJSON {
 
 	r, err := client.Get(url)
 	if err != nil {
-		logToFile(err.Error())
+		logger.Warn("Bins not fetched", "err", err)
 		return out
 	}
 	defer r.Body.Close()
@@ -108,11 +336,14 @@ func getBins(bins int) []filesupport.PasteJSON {
 		// GO strings works with utf-8
 		if err = json.NewDecoder(strings.NewReader(string(b))).Decode(&out); err != nil {
 			if strings.Contains(string(b), slowDown) || string(b) == "" {
-				logToFile("Slow down!\n")
+				logger.Warn("Slow down!")
 			} else {
 				// Error on marshalling JSON
-				s := fmt.Sprintf("\n%s\n", string(b))
-				logToFile(s)
+				body := b
+				if len(body) > maxLoggedBody {
+					body = body[:maxLoggedBody]
+				}
+				logger.Warn("Invalid response of the scraping API", "err", err, "body", string(body))
 			}
 		}
 	}
@@ -121,42 +352,245 @@ func getBins(bins int) []filesupport.PasteJSON {
 
 // Set the program to fetch `bins` bins every `interval` seconds
 func run(interval int, bins int) {
+	// Reload the rules on SIGHUP
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
 	parseBins := func() {
+		reloadRules()
+		savePending()
+		indexPending()
+		syncSaved()
 		for _, v := range getBins(bins) {
 			pasteSearcher(&v)
 		}
+		saveSuppressStats()
+		enforceRetention()
 	}
 
 	// First run
 	parseBins()
-	logToFile("Done!\n")
+	logger.Info("Done!")
+
+	// Run every 'interval' seconds, reload the rules at once on SIGHUP
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	for {
+		select {
+		case <-sighup:
+			atomic.StoreInt32(&reloadRequested, 1)
+			reloadRules()
+		case <-ticker.C:
+			logger.Debug("Restarting...")
+			parseBins()
+			logger.Info("Done!")
+		}
+	}
+}
+
+// Report an event in the log view and in the log file, or on stdout without the TUI
+func report(level logging.Level, s string, fields ...interface{}) {
+	if headless {
+		fmt.Println(s)
+	}
+	gui.PrintTo("log", s)
+	logger.Log(level, s, fields...)
+}
+
+// Set up the log file
+func openLogger() *logging.Logger {
+	l := &logging.Logger{
+		Path:    *logPath,
+		JSON:    *logFormat == "json",
+		MaxSize: int64(*logMaxSize),
+		MaxAge:  *logMaxAge,
+		Backups: *logBackups,
+		OnError: func(err error) {
+			if headless {
+				fmt.Fprintln(os.Stderr, "Log file not writable:", err)
+			} else {
+				gui.PrintTo("log", "Log file not writable: "+err.Error())
+			}
+		},
+	}
+	if l.Path == "" {
+		l.Path = filepath.Join(*outputTo, filesupport.MetaDir, "pastego.log")
+	}
+	l.Path, _ = filepath.Abs(l.Path)
+	l.Level, _ = logging.ParseLevel(*logLevel)
+	return l
+}
 
-	// Run every 'interval' seconds
-	for range time.NewTicker(time.Duration(interval) * time.Second).C {
-		logToFile("Restarting...")
-		parseBins()
-		logToFile("Done!\n")
+// Load the rules from the rules file and the search expressions
+func loadMatcher() *rules.Matcher {
+	normalizer, err := normalize.New(strings.Split(*normSteps, ","))
+	if err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+	m := &rules.Matcher{
+		CaseInsensitive: *caseInsens,
+		Normalizer:      normalizer,
+		DecodeDepth:     *decodeDepth,
+		DecodeMaxSize:   *decodeSize,
 	}
+	for _, def := range *macroDefs {
+		name, expr, err := rules.ParseMacro(def)
+		if err != nil {
+			kingpin.Fatalf("%s", err)
+		}
+		macros[name] = expr
+	}
+	if *rulesFile != "" {
+		if info, err := os.Stat(*rulesFile); err == nil {
+			rulesModTime = info.ModTime()
+		}
+		if m.Rules, err = rules.Load(*rulesFile, macros); err != nil {
+			kingpin.Fatalf("%s", err)
+		}
+	} else if *searchFor == "" {
+		*searchFor = "pass"
+	}
+	for _, r := range rules.FromSearch(*searchFor) {
+		if err := r.Expand(macros); err != nil {
+			kingpin.Fatalf("%s: %s", r.Expr, err)
+		}
+		searchRules = append(searchRules, r)
+	}
+	m.Rules = append(m.Rules, searchRules...)
+	return m
 }
 
-// Wrapper to avoid writing log function calls :)
-func logToFile(s string) {
-	filesupport.LogToFile(s)
+// Open the store of the saved bins
+func openStore() filesupport.Store {
+	if *storeURL == "" {
+		return filesupport.NewLocal(*outputTo)
+	}
+	s, err := filesupport.NewS3(*storeURL, *s3Endpoint, *s3Region)
+	if err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+	return s
+}
+
+// Load the suppressions and their stats
+func loadSuppressions() *suppress.List {
+	if *suppressTo == "" {
+		return nil
+	}
+	l, err := suppress.Load(*suppressTo, macros)
+	if err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+	if err := l.LoadStats(suppressStats()); err != nil {
+		kingpin.Fatalf("%s", err)
+	}
+	return l
+}
+
+// File of the stats of the suppressions
+func suppressStats() string {
+	if *statsTo != "" {
+		return *statsTo
+	}
+	return filepath.Join(*outputTo, filesupport.MetaDir, "suppressions-stats.json")
+}
+
+// Bins suppressed since the stats were saved
+var suppressed int
+
+// Save the stats of the suppressions, once per cycle and only if a bin was suppressed
+func saveSuppressStats() {
+	if suppressed == 0 {
+		return
+	}
+	if err := suppressions.SaveStats(suppressStats()); err != nil {
+		logger.Error("Stats of the suppressions not saved", "err", err)
+		return
+	}
+	suppressed = 0
+}
+
+// Reload the rules file when modified or on SIGHUP: an invalid file is rejected and the current rules are kept.
+// Must be called between the cycles of run()
+func reloadRules() {
+	if *rulesFile == "" {
+		return
+	}
+	info, err := os.Stat(*rulesFile)
+	if err != nil {
+		logger.Warn("Rules not reloaded", "err", err)
+		return
+	}
+	if atomic.SwapInt32(&reloadRequested, 0) == 0 && info.ModTime().Equal(rulesModTime) {
+		return
+	}
+	rulesModTime = info.ModTime()
+	rs, err := rules.Load(*rulesFile, macros)
+	if err != nil {
+		report(logging.Error, "Rules not reloaded, keeping the current ones: "+err.Error())
+		return
+	}
+	rs = append(rs, searchRules...)
+	s := "Rules reloaded"
+	if diff := rules.Diff(matcher.Rules, rs); diff != "" {
+		s += ": " + diff
+	} else {
+		s += ": no changes"
+	}
+	matcher.Rules = rs
+	report(logging.Info, s)
 }
 
 func main() {
-	kingpin.Parse()
-	logToFile(`
+	command := kingpin.Parse()
+	logger = openLogger()
+	defer logger.Close()
+	filesupport.Compress = *compress
+	if *keyFile != "" {
+		key, err := filesupport.LoadKey(*keyFile)
+		if err != nil {
+			kingpin.Fatalf("%s", err)
+		}
+		filesupport.Key = key
+	}
+	store = openStore()
+	matcher = loadMatcher()
+	suppressions = loadSuppressions()
+	retentionPolicy = loadRetention()
+	switch command {
+	case rulesTestCmd.FullCommand():
+		os.Exit(testRules(*rulesTestSamples))
+	case scanCmd.FullCommand():
+		os.Exit(scan(*scanPaths))
+	case rulesExplainCmd.FullCommand():
+		os.Exit(explainRule(*rulesExplainRule, *rulesExplainFile))
+	case replayCmd.FullCommand():
+		os.Exit(replay(*replaySource, *replaySpeed))
+	case searchCmd.FullCommand():
+		os.Exit(search(*searchQuery, *searchLimit))
+	case importCmd.FullCommand():
+		os.Exit(importFolder(*importDir))
+	case exportCmd.FullCommand():
+		os.Exit(exportBins(*exportFile, *exportFormat))
+	case purgeCmd.FullCommand():
+		os.Exit(purge(*purgeDryRun))
+	case suppressCmd.FullCommand():
+		listSuppressions()
+		return
+	}
 
-		██████╗  █████╗ ███████╗████████╗███████╗ ██████╗  ██████╗
-		██╔══██╗██╔══██╗██╔════╝╚══██╔══╝██╔════╝██╔════╝ ██╔═══██╗
-		██████╔╝███████║███████╗   ██║   █████╗  ██║  ███╗██║   ██║
-		██╔═══╝ ██╔══██║╚════██║   ██║   ██╔══╝  ██║   ██║██║   ██║
-		██║     ██║  ██║███████║   ██║   ███████╗╚██████╔╝╚██████╔╝
-		╚═╝     ╚═╝  ╚═╝╚══════╝   ╚═╝   ╚══════╝ ╚═════╝  ╚═════╝
-	`)
+	fmt.Fprintln(os.Stderr, "Log file:", logger.Path)
+	logger.Info("Started", "output", *outputTo, "store", store)
+
+	findingsDB = openFindings()
+	defer findingsDB.Close()
+	importSaved()
+	gui.DB = findingsDB
+	gui.LogPath = logger.Path
+	gui.Search = func(query string) ([]*filesupport.PasteMeta, error) {
+		return searchBins(query, 0)
+	}
 
 	// Without a PRO account try to increase the first args and decrease the second.
 	go run(150, 250)
-	gui.SetGui(*outputTo)
+	gui.SetGui(store)
 }
@@ -0,0 +1,69 @@
+package pegmatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Search terms are converted to upper case before matching: PasteContentString must be upper case too
+var CaseInsensitive bool = false
+
+// Func is a function callable from an expression, i.e. 'domain(@corp.txt)':
+// receives the argument between the parenthesis and the content of the bin
+type Func func(arg string, content string) (int, error)
+
+var funcs = map[string]Func{}
+
+// RegisterFunc makes a function available to the expressions with the given name
+func RegisterFunc(name string, f Func) {
+	funcs[name] = f
+}
+
+// Check if the content of the bin contains a term
+func (c *current) containsTerm(term string) *Node {
+	node := &Node{Text: string(c.text)}
+	if CaseInsensitive {
+		term = strings.ToUpper(term)
+	}
+	if c.tree() {
+		node.Hits = indexAll(PasteContentString, term)
+		node.Value = len(node.Hits) > 0
+	} else {
+		node.Value = strings.Contains(PasteContentString, term)
+	}
+	return node
+}
+
+// Call a registered function and compare the result: with no comparison matches when the result is > 0
+func (c *current) call(name string, arg string, cmp interface{}) (*Node, error) {
+	f, ok := funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	n, err := f(arg, PasteContentString)
+	if err != nil {
+		return nil, err
+	}
+	node := &Node{Text: string(c.text)}
+	if cmp == nil {
+		node.Value = n > 0
+		return node, nil
+	}
+	cmpSl := toIfaceSlice(cmp)
+	op, v := cmpSl[1].(string), cmpSl[3].(int)
+	switch op {
+	case ">=":
+		node.Value = n >= v
+	case "<=":
+		node.Value = n <= v
+	case "==":
+		node.Value = n == v
+	case "!=":
+		node.Value = n != v
+	case ">":
+		node.Value = n > v
+	default:
+		node.Value = n < v
+	}
+	return node, nil
+}
@@ -0,0 +1,246 @@
+package pegmatch
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Leetspeak characters and their letter, 'l' and 'i' are folded together like '1' and '!'
+var leet = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '9': 'g', '#': 'h',
+	'1': 'i', '!': 'i', '|': 'i', 'l': 'i', '0': 'o', '5': 's', '$': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+// Leetspeak version of the last content: computed once for all the expressions
+var (
+	leetContent, leetSource string
+	leetOffsets             []int
+)
+
+// Leetspeak version of the text, with the offset in the text of every byte of the folded text and of its end:
+// folding can change the length of the runes
+func foldLeet(s string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		r = unicode.ToLower(r)
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+		n, _ := b.WriteRune(r)
+		for ; n > 0; n-- {
+			offsets = append(offsets, i)
+		}
+	}
+	return b.String(), append(offsets, len(s))
+}
+
+// Parse a fuzzy term, i.e. '~2l'password”, and search it in the content of the bin
+func (c *current) fuzzyMatch(term string) *Node {
+	node := &Node{Text: term}
+	term = term[1:]
+	maxDist := 0
+	if term[0] >= '0' && term[0] <= '9' {
+		maxDist = int(term[0] - '0')
+		term = term[1:]
+	}
+	useLeet := term[0] == 'l'
+	if useLeet {
+		term = term[1:]
+	}
+	term = term[1 : len(term)-1]
+
+	content := PasteContentString
+	if useLeet {
+		if leetSource != PasteContentString || leetContent == "" {
+			leetContent, leetOffsets = foldLeet(PasteContentString)
+			leetSource = PasteContentString
+		}
+		content = leetContent
+		term, _ = foldLeet(term)
+	} else if CaseInsensitive {
+		term = strings.ToUpper(term)
+	}
+	switch {
+	case c.tree() && maxDist == 0:
+		node.Hits = indexAll(content, term)
+		node.Value = len(node.Hits) > 0
+	case c.tree():
+		node.Hits = approxHits(content, []rune(term), maxDist)
+		node.Value = len(node.Hits) > 0
+	case maxDist == 0:
+		node.Value = strings.Contains(content, term)
+	default:
+		node.Value = approxContains(content, []rune(term), maxDist)
+	}
+	// Spans of the original content
+	if useLeet {
+		for i, h := range node.Hits {
+			node.Hits[i] = Span{leetOffsets[h.Start], leetOffsets[h.End]}
+		}
+	}
+	return node
+}
+
+// Check if the text contains the pattern with at most 'k' edits
+func approxContains(text string, pattern []rune, k int) bool {
+	m := len(pattern)
+	if k >= m {
+		return true
+	}
+	if m > 64 {
+		return sellers(text, pattern, k)
+	}
+	// Myers' bit-parallel algorithm: one pass over the text
+	var ascii [128]uint64
+	other := map[rune]uint64{}
+	for i, r := range pattern {
+		if r < 128 {
+			ascii[r] |= 1 << uint(i)
+		} else {
+			other[r] |= 1 << uint(i)
+		}
+	}
+	last := uint64(1) << uint(m-1)
+	pv, mv := ^uint64(0), uint64(0)
+	score := m
+	for _, r := range text {
+		var eq uint64
+		if r < 128 {
+			eq = ascii[r]
+		} else {
+			eq = other[r]
+		}
+		xv := eq | mv
+		xh := (((eq & pv) + pv) ^ pv) | eq
+		ph := mv | ^(xh | pv)
+		mh := pv & xh
+		if ph&last != 0 {
+			score++
+		} else if mh&last != 0 {
+			score--
+		}
+		ph <<= 1
+		mh <<= 1
+		pv = mh | ^(xv | ph)
+		mv = ph & xv
+		if score <= k {
+			return true
+		}
+	}
+	return false
+}
+
+// Dynamic programming version for long patterns
+func sellers(text string, pattern []rune, k int) bool {
+	m := len(pattern)
+	prev := make([]int, m+1)
+	cur := make([]int, m+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	for _, r := range text {
+		cur[0] = 0
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if pattern[i-1] == r {
+				cost = 0
+			}
+			cur[i] = prev[i-1] + cost
+			if prev[i]+1 < cur[i] {
+				cur[i] = prev[i] + 1
+			}
+			if cur[i-1]+1 < cur[i] {
+				cur[i] = cur[i-1] + 1
+			}
+		}
+		if cur[m] <= k {
+			return true
+		}
+		prev, cur = cur, prev
+	}
+	return false
+}
+
+// Spans of the matches with at most 'k' edits, overlapping matches are reported once
+func approxHits(text string, pattern []rune, k int) []Span {
+	var hits []Span
+	m := len(pattern)
+	prev := make([]int, m+1)
+	cur := make([]int, m+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	matching := false
+	for off, r := range text {
+		cur[0] = 0
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if pattern[i-1] == r {
+				cost = 0
+			}
+			cur[i] = prev[i-1] + cost
+			if prev[i]+1 < cur[i] {
+				cur[i] = prev[i] + 1
+			}
+			if cur[i-1]+1 < cur[i] {
+				cur[i] = cur[i-1] + 1
+			}
+		}
+		// Report the best match of a run of matching positions
+		end := off + utf8.RuneLen(r)
+		if cur[m] <= k {
+			if !matching {
+				hits = append(hits, Span{approxStart(text[:end], pattern, k), end})
+			} else if cur[m] < prev[m] {
+				hits[len(hits)-1] = Span{approxStart(text[:end], pattern, k), end}
+			}
+		}
+		matching = cur[m] <= k
+		prev, cur = cur, prev
+	}
+	return hits
+}
+
+// Start of the closest match of the pattern ending with the text
+func approxStart(text string, pattern []rune, k int) int {
+	m := len(pattern)
+	best, start := k+1, len(text)
+	size := 0
+	for i := len(text); i > 0 && size < m+k; size++ {
+		_, n := utf8.DecodeLastRuneInString(text[:i])
+		i -= n
+		if d := editDistance([]rune(text[i:]), pattern); d < best {
+			best, start = d, i
+		}
+	}
+	return start
+}
+
+// Levenshtein distance of two strings
+func editDistance(a []rune, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
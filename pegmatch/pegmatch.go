@@ -36,14 +36,22 @@ func toIfaceSlice(v interface{}) []interface{} {
 	return v.([]interface{})
 }
 
-func eval(first, rest interface{}) bool {
-	l := first.(bool)
+func eval(first, rest interface{}) *Node {
+	l := first.(*Node)
 	restSl := toIfaceSlice(rest)
+	var last *Node
 	for _, v := range restSl {
 		restExpr := toIfaceSlice(v)
-		r := restExpr[3].(bool)
+		r := restExpr[3].(*Node)
 		op := restExpr[1].(string)
-		l = ops[op](l, r)
+		// Chains of the same operator are a single node: 'a && b && c'
+		if last != nil && last.Op == op {
+			last.Children = append(last.Children, r)
+		} else {
+			last = &Node{Op: op, Value: l.Value, Children: []*Node{l, r}}
+			l = last
+		}
+		l.Value = ops[op](l.Value, r.Value)
 	}
 	return l
 }
@@ -52,23 +60,23 @@ var g = &grammar{
 	rules: []*rule{
 		{
 			name: "Input",
-			pos:  position{line: 41, col: 1, offset: 785},
+			pos:  position{line: 50, col: 1, offset: 1238},
 			expr: &actionExpr{
-				pos: position{line: 41, col: 10, offset: 794},
+				pos: position{line: 50, col: 10, offset: 1247},
 				run: (*parser).callonInput1,
 				expr: &seqExpr{
-					pos: position{line: 41, col: 10, offset: 794},
+					pos: position{line: 50, col: 10, offset: 1247},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 41, col: 10, offset: 794},
+							pos:   position{line: 50, col: 10, offset: 1247},
 							label: "expr",
 							expr: &ruleRefExpr{
-								pos:  position{line: 41, col: 15, offset: 799},
+								pos:  position{line: 50, col: 15, offset: 1252},
 								name: "Expr",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 41, col: 20, offset: 804},
+							pos:  position{line: 50, col: 20, offset: 1257},
 							name: "EOF",
 						},
 					},
@@ -77,47 +85,47 @@ var g = &grammar{
 		},
 		{
 			name: "Expr",
-			pos:  position{line: 45, col: 1, offset: 834},
+			pos:  position{line: 57, col: 1, offset: 1350},
 			expr: &actionExpr{
-				pos: position{line: 45, col: 9, offset: 842},
+				pos: position{line: 57, col: 9, offset: 1358},
 				run: (*parser).callonExpr1,
 				expr: &seqExpr{
-					pos: position{line: 45, col: 9, offset: 842},
+					pos: position{line: 57, col: 9, offset: 1358},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 45, col: 9, offset: 842},
+							pos:  position{line: 57, col: 9, offset: 1358},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 45, col: 11, offset: 844},
+							pos:   position{line: 57, col: 11, offset: 1360},
 							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 45, col: 17, offset: 850},
+								pos:  position{line: 57, col: 17, offset: 1366},
 								name: "Term",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 45, col: 22, offset: 855},
+							pos:   position{line: 57, col: 22, offset: 1371},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 45, col: 27, offset: 860},
+								pos: position{line: 57, col: 27, offset: 1376},
 								expr: &seqExpr{
-									pos: position{line: 45, col: 29, offset: 862},
+									pos: position{line: 57, col: 29, offset: 1378},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 45, col: 29, offset: 862},
+											pos:  position{line: 57, col: 29, offset: 1378},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 45, col: 31, offset: 864},
+											pos:  position{line: 57, col: 31, offset: 1380},
 											name: "BoolOp",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 45, col: 38, offset: 871},
+											pos:  position{line: 57, col: 38, offset: 1387},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 45, col: 40, offset: 873},
+											pos:  position{line: 57, col: 40, offset: 1389},
 											name: "Term",
 										},
 									},
@@ -125,7 +133,7 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 45, col: 48, offset: 881},
+							pos:  position{line: 57, col: 48, offset: 1397},
 							name: "_",
 						},
 					},
@@ -134,32 +142,44 @@ var g = &grammar{
 		},
 		{
 			name: "Term",
-			pos:  position{line: 49, col: 1, offset: 922},
+			pos:  position{line: 61, col: 1, offset: 1438},
 			expr: &choiceExpr{
-				pos: position{line: 49, col: 9, offset: 930},
+				pos: position{line: 61, col: 9, offset: 1446},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 49, col: 9, offset: 930},
+						pos: position{line: 61, col: 9, offset: 1446},
 						run: (*parser).callonTerm2,
+						expr: &labeledExpr{
+							pos:   position{line: 61, col: 9, offset: 1446},
+							label: "fuzzy",
+							expr: &ruleRefExpr{
+								pos:  position{line: 61, col: 15, offset: 1452},
+								name: "Fuzzy",
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 63, col: 5, offset: 1486},
+						run: (*parser).callonTerm5,
 						expr: &seqExpr{
-							pos: position{line: 49, col: 9, offset: 930},
+							pos: position{line: 63, col: 5, offset: 1486},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 49, col: 9, offset: 930},
+									pos:        position{line: 63, col: 5, offset: 1486},
 									val:        "(",
 									ignoreCase: false,
 									want:       "\"(\"",
 								},
 								&labeledExpr{
-									pos:   position{line: 49, col: 13, offset: 934},
+									pos:   position{line: 63, col: 9, offset: 1490},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 49, col: 18, offset: 939},
+										pos:  position{line: 63, col: 14, offset: 1495},
 										name: "Expr",
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 49, col: 23, offset: 944},
+									pos:        position{line: 63, col: 19, offset: 1500},
 									val:        ")",
 									ignoreCase: false,
 									want:       "\")\"",
@@ -168,30 +188,30 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 51, col: 5, offset: 975},
-						run: (*parser).callonTerm8,
+						pos: position{line: 65, col: 5, offset: 1531},
+						run: (*parser).callonTerm11,
 						expr: &seqExpr{
-							pos: position{line: 51, col: 5, offset: 975},
+							pos: position{line: 65, col: 5, offset: 1531},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 51, col: 5, offset: 975},
+									pos:        position{line: 65, col: 5, offset: 1531},
 									val:        "'",
 									ignoreCase: false,
 									want:       "\"'\"",
 								},
 								&oneOrMoreExpr{
-									pos: position{line: 51, col: 9, offset: 979},
+									pos: position{line: 65, col: 9, offset: 1535},
 									expr: &seqExpr{
-										pos: position{line: 51, col: 10, offset: 980},
+										pos: position{line: 65, col: 10, offset: 1536},
 										exprs: []interface{}{
 											&ruleRefExpr{
-												pos:  position{line: 51, col: 10, offset: 980},
+												pos:  position{line: 65, col: 10, offset: 1536},
 												name: "Search",
 											},
 											&zeroOrOneExpr{
-												pos: position{line: 51, col: 17, offset: 987},
+												pos: position{line: 65, col: 17, offset: 1543},
 												expr: &ruleRefExpr{
-													pos:  position{line: 51, col: 17, offset: 987},
+													pos:  position{line: 65, col: 17, offset: 1543},
 													name: "_",
 												},
 											},
@@ -199,7 +219,7 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 51, col: 22, offset: 992},
+									pos:        position{line: 65, col: 22, offset: 1548},
 									val:        "'",
 									ignoreCase: false,
 									want:       "\"'\"",
@@ -208,43 +228,52 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 55, col: 5, offset: 1127},
-						run: (*parser).callonTerm17,
+						pos: position{line: 69, col: 5, offset: 1661},
+						run: (*parser).callonTerm20,
+						expr: &labeledExpr{
+							pos:   position{line: 69, col: 5, offset: 1661},
+							label: "call",
+							expr: &ruleRefExpr{
+								pos:  position{line: 69, col: 10, offset: 1666},
+								name: "Call",
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 71, col: 5, offset: 1698},
+						run: (*parser).callonTerm23,
 						expr: &labeledExpr{
-							pos:   position{line: 55, col: 5, offset: 1127},
+							pos:   position{line: 71, col: 5, offset: 1698},
 							label: "boolean",
 							expr: &ruleRefExpr{
-								pos:  position{line: 55, col: 13, offset: 1135},
+								pos:  position{line: 71, col: 13, offset: 1706},
 								name: "Search",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 57, col: 5, offset: 1173},
-						run: (*parser).callonTerm20,
+						pos: position{line: 73, col: 5, offset: 1744},
+						run: (*parser).callonTerm26,
 						expr: &seqExpr{
-							pos: position{line: 57, col: 5, offset: 1173},
+							pos: position{line: 73, col: 5, offset: 1744},
 							exprs: []interface{}{
 								&labeledExpr{
-									pos:   position{line: 57, col: 5, offset: 1173},
+									pos:   position{line: 73, col: 5, offset: 1744},
 									label: "notop",
-									expr: &zeroOrOneExpr{
-										pos: position{line: 57, col: 11, offset: 1179},
-										expr: &ruleRefExpr{
-											pos:  position{line: 57, col: 11, offset: 1179},
-											name: "NotOp",
-										},
+									expr: &ruleRefExpr{
+										pos:  position{line: 73, col: 11, offset: 1750},
+										name: "NotOp",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 57, col: 18, offset: 1186},
+									pos:  position{line: 73, col: 17, offset: 1756},
 									name: "_",
 								},
 								&labeledExpr{
-									pos:   position{line: 57, col: 20, offset: 1188},
+									pos:   position{line: 73, col: 19, offset: 1758},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 57, col: 25, offset: 1193},
+										pos:  position{line: 73, col: 24, offset: 1763},
 										name: "Expr",
 									},
 								},
@@ -256,21 +285,21 @@ var g = &grammar{
 		},
 		{
 			name: "BoolOp",
-			pos:  position{line: 62, col: 1, offset: 1237},
+			pos:  position{line: 78, col: 1, offset: 1800},
 			expr: &actionExpr{
-				pos: position{line: 62, col: 11, offset: 1247},
+				pos: position{line: 78, col: 11, offset: 1810},
 				run: (*parser).callonBoolOp1,
 				expr: &choiceExpr{
-					pos: position{line: 62, col: 13, offset: 1249},
+					pos: position{line: 78, col: 13, offset: 1812},
 					alternatives: []interface{}{
 						&litMatcher{
-							pos:        position{line: 62, col: 13, offset: 1249},
+							pos:        position{line: 78, col: 13, offset: 1812},
 							val:        "&&",
 							ignoreCase: false,
 							want:       "\"&&\"",
 						},
 						&litMatcher{
-							pos:        position{line: 62, col: 20, offset: 1256},
+							pos:        position{line: 78, col: 20, offset: 1819},
 							val:        "||",
 							ignoreCase: false,
 							want:       "\"||\"",
@@ -279,19 +308,264 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "Fuzzy",
+			pos:  position{line: 86, col: 1, offset: 2062},
+			expr: &actionExpr{
+				pos: position{line: 86, col: 10, offset: 2071},
+				run: (*parser).callonFuzzy1,
+				expr: &seqExpr{
+					pos: position{line: 86, col: 10, offset: 2071},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 86, col: 10, offset: 2071},
+							val:        "~",
+							ignoreCase: false,
+							want:       "\"~\"",
+						},
+						&choiceExpr{
+							pos: position{line: 86, col: 16, offset: 2077},
+							alternatives: []interface{}{
+								&seqExpr{
+									pos: position{line: 86, col: 16, offset: 2077},
+									exprs: []interface{}{
+										&charClassMatcher{
+											pos:        position{line: 86, col: 16, offset: 2077},
+											val:        "[0-9]",
+											ranges:     []rune{'0', '9'},
+											ignoreCase: false,
+											inverted:   false,
+										},
+										&zeroOrOneExpr{
+											pos: position{line: 86, col: 22, offset: 2083},
+											expr: &litMatcher{
+												pos:        position{line: 86, col: 22, offset: 2083},
+												val:        "l",
+												ignoreCase: false,
+												want:       "\"l\"",
+											},
+										},
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 86, col: 29, offset: 2090},
+									val:        "l",
+									ignoreCase: false,
+									want:       "\"l\"",
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 86, col: 35, offset: 2096},
+							val:        "'",
+							ignoreCase: false,
+							want:       "\"'\"",
+						},
+						&oneOrMoreExpr{
+							pos: position{line: 86, col: 39, offset: 2100},
+							expr: &charClassMatcher{
+								pos:        position{line: 86, col: 39, offset: 2100},
+								val:        "[^']",
+								chars:      []rune{'\''},
+								ignoreCase: false,
+								inverted:   true,
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 86, col: 45, offset: 2106},
+							val:        "'",
+							ignoreCase: false,
+							want:       "\"'\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Call",
+			pos:  position{line: 94, col: 1, offset: 2346},
+			expr: &actionExpr{
+				pos: position{line: 94, col: 9, offset: 2354},
+				run: (*parser).callonCall1,
+				expr: &seqExpr{
+					pos: position{line: 94, col: 9, offset: 2354},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 94, col: 9, offset: 2354},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 14, offset: 2359},
+								name: "Ident",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 94, col: 20, offset: 2365},
+							val:        "(",
+							ignoreCase: false,
+							want:       "\"(\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 94, col: 24, offset: 2369},
+							label: "arg",
+							expr: &ruleRefExpr{
+								pos:  position{line: 94, col: 28, offset: 2373},
+								name: "Arg",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 94, col: 32, offset: 2377},
+							val:        ")",
+							ignoreCase: false,
+							want:       "\")\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 94, col: 36, offset: 2381},
+							label: "cmp",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 94, col: 40, offset: 2385},
+								expr: &seqExpr{
+									pos: position{line: 94, col: 42, offset: 2387},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 94, col: 42, offset: 2387},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 94, col: 44, offset: 2389},
+											name: "CmpOp",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 94, col: 50, offset: 2395},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 94, col: 52, offset: 2397},
+											name: "Number",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Ident",
+			pos:  position{line: 98, col: 1, offset: 2464},
+			expr: &actionExpr{
+				pos: position{line: 98, col: 10, offset: 2473},
+				run: (*parser).callonIdent1,
+				expr: &oneOrMoreExpr{
+					pos: position{line: 98, col: 10, offset: 2473},
+					expr: &charClassMatcher{
+						pos:        position{line: 98, col: 10, offset: 2473},
+						val:        "[a-z]",
+						ranges:     []rune{'a', 'z'},
+						ignoreCase: false,
+						inverted:   false,
+					},
+				},
+			},
+		},
+		{
+			name: "Arg",
+			pos:  position{line: 102, col: 1, offset: 2516},
+			expr: &actionExpr{
+				pos: position{line: 102, col: 8, offset: 2523},
+				run: (*parser).callonArg1,
+				expr: &zeroOrMoreExpr{
+					pos: position{line: 102, col: 8, offset: 2523},
+					expr: &charClassMatcher{
+						pos:        position{line: 102, col: 8, offset: 2523},
+						val:        "[^()]",
+						chars:      []rune{'(', ')'},
+						ignoreCase: false,
+						inverted:   true,
+					},
+				},
+			},
+		},
+		{
+			name: "CmpOp",
+			pos:  position{line: 106, col: 1, offset: 2585},
+			expr: &actionExpr{
+				pos: position{line: 106, col: 10, offset: 2594},
+				run: (*parser).callonCmpOp1,
+				expr: &choiceExpr{
+					pos: position{line: 106, col: 12, offset: 2596},
+					alternatives: []interface{}{
+						&litMatcher{
+							pos:        position{line: 106, col: 12, offset: 2596},
+							val:        ">=",
+							ignoreCase: false,
+							want:       "\">=\"",
+						},
+						&litMatcher{
+							pos:        position{line: 106, col: 19, offset: 2603},
+							val:        "<=",
+							ignoreCase: false,
+							want:       "\"<=\"",
+						},
+						&litMatcher{
+							pos:        position{line: 106, col: 26, offset: 2610},
+							val:        "==",
+							ignoreCase: false,
+							want:       "\"==\"",
+						},
+						&litMatcher{
+							pos:        position{line: 106, col: 33, offset: 2617},
+							val:        "!=",
+							ignoreCase: false,
+							want:       "\"!=\"",
+						},
+						&litMatcher{
+							pos:        position{line: 106, col: 40, offset: 2624},
+							val:        ">",
+							ignoreCase: false,
+							want:       "\">\"",
+						},
+						&litMatcher{
+							pos:        position{line: 106, col: 46, offset: 2630},
+							val:        "<",
+							ignoreCase: false,
+							want:       "\"<\"",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Number",
+			pos:  position{line: 110, col: 1, offset: 2672},
+			expr: &actionExpr{
+				pos: position{line: 110, col: 11, offset: 2682},
+				run: (*parser).callonNumber1,
+				expr: &oneOrMoreExpr{
+					pos: position{line: 110, col: 11, offset: 2682},
+					expr: &charClassMatcher{
+						pos:        position{line: 110, col: 11, offset: 2682},
+						val:        "[0-9]",
+						ranges:     []rune{'0', '9'},
+						ignoreCase: false,
+						inverted:   false,
+					},
+				},
+			},
+		},
 		{
 			name: "Search",
-			pos:  position{line: 66, col: 1, offset: 1298},
+			pos:  position{line: 114, col: 1, offset: 2734},
 			expr: &choiceExpr{
-				pos: position{line: 66, col: 11, offset: 1308},
+				pos: position{line: 114, col: 11, offset: 2744},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 66, col: 11, offset: 1308},
+						pos: position{line: 114, col: 11, offset: 2744},
 						run: (*parser).callonSearch2,
 						expr: &oneOrMoreExpr{
-							pos: position{line: 66, col: 11, offset: 1308},
+							pos: position{line: 114, col: 11, offset: 2744},
 							expr: &charClassMatcher{
-								pos:        position{line: 66, col: 11, offset: 1308},
+								pos:        position{line: 114, col: 11, offset: 2744},
 								val:        "[A-Za-z0-9!@#$%^?/*-+.><{}]",
 								chars:      []rune{'!', '@', '#', '$', '%', '^', '?', '/', '.', '>', '<', '{', '}'},
 								ranges:     []rune{'A', 'Z', 'a', 'z', '0', '9', '*', '+'},
@@ -301,24 +575,74 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 68, col: 5, offset: 1412},
+						pos: position{line: 116, col: 5, offset: 2826},
 						run: (*parser).callonSearch5,
 						expr: &seqExpr{
-							pos: position{line: 68, col: 5, offset: 1412},
+							pos: position{line: 116, col: 5, offset: 2826},
+							exprs: []interface{}{
+								&ruleRefExpr{
+									pos:  position{line: 116, col: 5, offset: 2826},
+									name: "NotOp",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 116, col: 11, offset: 2832},
+									name: "_",
+								},
+								&labeledExpr{
+									pos:   position{line: 116, col: 13, offset: 2834},
+									label: "fuzzy",
+									expr: &ruleRefExpr{
+										pos:  position{line: 116, col: 19, offset: 2840},
+										name: "Fuzzy",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 118, col: 5, offset: 2879},
+						run: (*parser).callonSearch11,
+						expr: &seqExpr{
+							pos: position{line: 118, col: 5, offset: 2879},
 							exprs: []interface{}{
 								&ruleRefExpr{
-									pos:  position{line: 68, col: 5, offset: 1412},
+									pos:  position{line: 118, col: 5, offset: 2879},
 									name: "NotOp",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 68, col: 11, offset: 1418},
+									pos:  position{line: 118, col: 11, offset: 2885},
 									name: "_",
 								},
 								&labeledExpr{
-									pos:   position{line: 68, col: 13, offset: 1420},
+									pos:   position{line: 118, col: 13, offset: 2887},
+									label: "call",
+									expr: &ruleRefExpr{
+										pos:  position{line: 118, col: 18, offset: 2892},
+										name: "Call",
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 120, col: 5, offset: 2929},
+						run: (*parser).callonSearch17,
+						expr: &seqExpr{
+							pos: position{line: 120, col: 5, offset: 2929},
+							exprs: []interface{}{
+								&ruleRefExpr{
+									pos:  position{line: 120, col: 5, offset: 2929},
+									name: "NotOp",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 120, col: 11, offset: 2935},
+									name: "_",
+								},
+								&labeledExpr{
+									pos:   position{line: 120, col: 13, offset: 2937},
 									label: "search",
 									expr: &ruleRefExpr{
-										pos:  position{line: 68, col: 20, offset: 1427},
+										pos:  position{line: 120, col: 20, offset: 2944},
 										name: "Search",
 									},
 								},
@@ -330,12 +654,12 @@ var g = &grammar{
 		},
 		{
 			name: "NotOp",
-			pos:  position{line: 72, col: 1, offset: 1475},
+			pos:  position{line: 124, col: 1, offset: 2984},
 			expr: &actionExpr{
-				pos: position{line: 72, col: 10, offset: 1484},
+				pos: position{line: 124, col: 10, offset: 2993},
 				run: (*parser).callonNotOp1,
 				expr: &litMatcher{
-					pos:        position{line: 72, col: 10, offset: 1484},
+					pos:        position{line: 124, col: 10, offset: 2993},
 					val:        "~",
 					ignoreCase: false,
 					want:       "\"~\"",
@@ -345,11 +669,11 @@ var g = &grammar{
 		{
 			name:        "_",
 			displayName: "\"whitespace\"",
-			pos:         position{line: 76, col: 1, offset: 1524},
+			pos:         position{line: 128, col: 1, offset: 3033},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 76, col: 19, offset: 1542},
+				pos: position{line: 128, col: 19, offset: 3051},
 				expr: &charClassMatcher{
-					pos:        position{line: 76, col: 19, offset: 1542},
+					pos:        position{line: 128, col: 19, offset: 3051},
 					val:        "[ \\n\\t\\r]",
 					chars:      []rune{' ', '\n', '\t', '\r'},
 					ignoreCase: false,
@@ -359,11 +683,11 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 78, col: 1, offset: 1554},
+			pos:  position{line: 130, col: 1, offset: 3063},
 			expr: &notExpr{
-				pos: position{line: 78, col: 8, offset: 1561},
+				pos: position{line: 130, col: 8, offset: 3070},
 				expr: &anyMatcher{
-					line: 78, col: 9, offset: 1562,
+					line: 130, col: 9, offset: 3071,
 				},
 			},
 		},
@@ -371,7 +695,10 @@ var g = &grammar{
 }
 
 func (c *current) onInput1(expr interface{}) (interface{}, error) {
-	return expr, nil
+	if c.tree() {
+		return expr, nil
+	}
+	return expr.(*Node).Value, nil
 }
 
 func (p *parser) callonInput1() (interface{}, error) {
@@ -390,46 +717,66 @@ func (p *parser) callonExpr1() (interface{}, error) {
 	return p.cur.onExpr1(stack["first"], stack["rest"])
 }
 
-func (c *current) onTerm2(expr interface{}) (interface{}, error) {
-	return expr, nil
+func (c *current) onTerm2(fuzzy interface{}) (interface{}, error) {
+	return fuzzy, nil
 }
 
 func (p *parser) callonTerm2() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onTerm2(stack["expr"])
+	return p.cur.onTerm2(stack["fuzzy"])
+}
+
+func (c *current) onTerm5(expr interface{}) (interface{}, error) {
+	return expr, nil
+}
+
+func (p *parser) callonTerm5() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTerm5(stack["expr"])
 }
 
-func (c *current) onTerm8() (interface{}, error) {
+func (c *current) onTerm11() (interface{}, error) {
 	var sTemp = string(c.text)
 	sTemp = sTemp[1 : len(sTemp)-1]
-	return strings.Contains(PasteContentString, sTemp), nil
+	return c.containsTerm(sTemp), nil
 }
 
-func (p *parser) callonTerm8() (interface{}, error) {
+func (p *parser) callonTerm11() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onTerm8()
+	return p.cur.onTerm11()
 }
 
-func (c *current) onTerm17(boolean interface{}) (interface{}, error) {
+func (c *current) onTerm20(call interface{}) (interface{}, error) {
+	return call, nil
+}
+
+func (p *parser) callonTerm20() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTerm20(stack["call"])
+}
+
+func (c *current) onTerm23(boolean interface{}) (interface{}, error) {
 	return boolean, nil
 }
 
-func (p *parser) callonTerm17() (interface{}, error) {
+func (p *parser) callonTerm23() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onTerm17(stack["boolean"])
+	return p.cur.onTerm23(stack["boolean"])
 }
 
-func (c *current) onTerm20(notop, expr interface{}) (interface{}, error) {
-	return !eval(expr, nil), nil
+func (c *current) onTerm26(notop, expr interface{}) (interface{}, error) {
+	return not(expr), nil
 }
 
-func (p *parser) callonTerm20() (interface{}, error) {
+func (p *parser) callonTerm26() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onTerm20(stack["notop"], stack["expr"])
+	return p.cur.onTerm26(stack["notop"], stack["expr"])
 }
 
 func (c *current) onBoolOp1() (interface{}, error) {
@@ -442,8 +789,68 @@ func (p *parser) callonBoolOp1() (interface{}, error) {
 	return p.cur.onBoolOp1()
 }
 
+func (c *current) onFuzzy1() (interface{}, error) {
+	return c.fuzzyMatch(string(c.text)), nil
+}
+
+func (p *parser) callonFuzzy1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFuzzy1()
+}
+
+func (c *current) onCall1(name, arg, cmp interface{}) (interface{}, error) {
+	return c.call(name.(string), arg.(string), cmp)
+}
+
+func (p *parser) callonCall1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCall1(stack["name"], stack["arg"], stack["cmp"])
+}
+
+func (c *current) onIdent1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonIdent1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIdent1()
+}
+
+func (c *current) onArg1() (interface{}, error) {
+	return strings.TrimSpace(string(c.text)), nil
+}
+
+func (p *parser) callonArg1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onArg1()
+}
+
+func (c *current) onCmpOp1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonCmpOp1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onCmpOp1()
+}
+
+func (c *current) onNumber1() (interface{}, error) {
+	return strconv.Atoi(string(c.text))
+}
+
+func (p *parser) callonNumber1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNumber1()
+}
+
 func (c *current) onSearch2() (interface{}, error) {
-	return strings.Contains(PasteContentString, string(c.text)), nil
+	return c.containsTerm(string(c.text)), nil
 }
 
 func (p *parser) callonSearch2() (interface{}, error) {
@@ -452,14 +859,34 @@ func (p *parser) callonSearch2() (interface{}, error) {
 	return p.cur.onSearch2()
 }
 
-func (c *current) onSearch5(search interface{}) (interface{}, error) {
-	return !eval(search, nil), nil
+func (c *current) onSearch5(fuzzy interface{}) (interface{}, error) {
+	return not(fuzzy), nil
 }
 
 func (p *parser) callonSearch5() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onSearch5(stack["search"])
+	return p.cur.onSearch5(stack["fuzzy"])
+}
+
+func (c *current) onSearch11(call interface{}) (interface{}, error) {
+	return not(call), nil
+}
+
+func (p *parser) callonSearch11() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSearch11(stack["call"])
+}
+
+func (c *current) onSearch17(search interface{}) (interface{}, error) {
+	return not(search), nil
+}
+
+func (p *parser) callonSearch17() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSearch17(stack["search"])
 }
 
 func (c *current) onNotOp1() (interface{}, error) {
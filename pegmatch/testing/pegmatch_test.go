@@ -2,10 +2,15 @@ package pegmatch_test
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/notdodo/pastego/pegmatch"
+	"github.com/notdodo/pastego/watchlist"
 )
 
 func TestPegmatchSimple(t *testing.T) {
@@ -70,3 +75,96 @@ func TestPegmatchHard(t *testing.T) {
 		t.Error("failed")
 	}
 }
+
+func TestPegmatchInvalid(t *testing.T) {
+	// Invalid expressions are errors, not an endless recursion
+	m := []string{"", "&&", "a &&", ")", "a && )", "(a", "~"}
+	pegmatch.PasteContentString = "a"
+	for _, mtch := range m {
+		if _, err := pegmatch.ParseReader("", bytes.NewBufferString(mtch)); err == nil {
+			t.Error("invalid expression accepted", mtch)
+		}
+	}
+}
+
+func TestPegmatchFunctions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	corp := filepath.Join(dir, "corp.txt")
+	ioutil.WriteFile(corp, []byte("# our domains\ncorp.com\n10.1.0.0/16\n"), 0644)
+	pegmatch.RegisterFunc("domain", watchlist.Domains)
+	pegmatch.RegisterFunc("ip", watchlist.IPs)
+
+	m := map[string]bool{
+		"domain(@" + corp + ")":                   true,
+		"domain(@" + corp + ") == 1":              true,
+		"domain(@" + corp + ") >= 2":              false,
+		"ip(@" + corp + ")":                       true,
+		"domain(other.org) || ip(192.168.0.0/16)": false,
+		"quake && ~domain(corp.com)":              false,
+	}
+	pegmatch.PasteContentString = "quake: root@vpn.corp.com from 10.1.2.3"
+	for mtch, want := range m {
+		got, err := pegmatch.ParseReader("", bytes.NewBufferString(mtch))
+		if err != nil || got.(bool) != want {
+			t.Error("failed", mtch, err)
+		}
+	}
+	if _, err := pegmatch.ParseReader("", bytes.NewBufferString("nothere(x)")); err == nil {
+		t.Error("unknown function accepted")
+	}
+}
+
+func TestPegmatchFuzzy(t *testing.T) {
+	m := map[string]bool{
+		"~1'corpname'":                true,
+		"~3'c0rpnome'":                true,
+		"~2'c0rpnome'":                false,
+		"~l'password'":                true,
+		"~l'passw0rd' && ~1'corpnam'": true,
+		"~~l'password'":               false,
+		"~'corpname'":                 true,
+		"~2'internal secrets'":        true,
+	}
+	pegmatch.PasteContentString = "leaked from corp-name: p4$$w0rd, internal secret"
+	for mtch, want := range m {
+		got, err := pegmatch.ParseReader("", bytes.NewBufferString(mtch))
+		if err != nil || got.(bool) != want {
+			t.Error("failed", mtch, err)
+		}
+	}
+}
+
+func TestPegmatchExplain(t *testing.T) {
+	pegmatch.PasteContentString = "my password is: java, password"
+	tree, err := pegmatch.Explain("password && 'password is' && ~(include || java)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Op != "&&" || tree.Value || len(tree.Children) != 3 {
+		t.Fatal("root", tree)
+	}
+	pass, quoted, not := tree.Children[0], tree.Children[1], tree.Children[2]
+	if pass.Text != "password" || !pass.Value || !reflect.DeepEqual(pass.Hits, []pegmatch.Span{{Start: 3, End: 11}, {Start: 22, End: 30}}) {
+		t.Error("term", pass)
+	}
+	if not.Op != "~" || not.Value || not.Children[0].Op != "||" || !not.Children[0].Children[1].Value {
+		t.Error("not", not)
+	}
+	if !quoted.Value || !reflect.DeepEqual(quoted.Hits, []pegmatch.Span{{Start: 3, End: 14}}) {
+		t.Error("quoted", quoted)
+	}
+	tree, err = pegmatch.Explain("~1'pasword'")
+	if err != nil || !tree.Value || !reflect.DeepEqual(tree.Hits, []pegmatch.Span{{Start: 3, End: 11}, {Start: 22, End: 30}}) {
+		t.Error("fuzzy", tree, err)
+	}
+
+	// Folding changes the length of 'Ⱥ': the spans refer to the original content
+	pegmatch.PasteContentString = "ȺȺȺ p4$$w0rd ȺȺ"
+	for _, expr := range []string{"~l'password'", "~1l'pasword'"} {
+		tree, err = pegmatch.Explain(expr)
+		if err != nil || !tree.Value || !reflect.DeepEqual(tree.Hits, []pegmatch.Span{{Start: 7, End: 15}}) {
+			t.Error("leet", expr, tree, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package pegmatch
+
+import (
+	"strings"
+	"sync"
+)
+
+// Lock guards PasteContentString and CaseInsensitive: hold it while setting them and parsing
+var Lock sync.Mutex
+
+// Node of the expression tree with its truth value against PasteContentString
+type Node struct {
+	// Operator: "&&", "||", "~", empty for the terms
+	Op   string `json:"op,omitempty"`
+	Text string `json:"text,omitempty"`
+	// Value of the node
+	Value bool `json:"value"`
+	// Positions of the term in the content
+	Hits     []Span  `json:"hits,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Span of the content between the byte offsets Start and End
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Explain parses the expression and returns the tree evaluated against PasteContentString
+func Explain(expr string) (*Node, error) {
+	got, err := Parse("", []byte(expr), GlobalStore("tree", true))
+	if err != nil {
+		return nil, err
+	}
+	return got.(*Node), nil
+}
+
+// Negate a node
+func not(v interface{}) *Node {
+	n := v.(*Node)
+	return &Node{Op: "~", Value: !n.Value, Children: []*Node{n}}
+}
+
+// The whole tree is requested: collect the positions of the hits
+func (c *current) tree() bool {
+	v, _ := c.globalStore["tree"].(bool)
+	return v
+}
+
+// Spans of every occurrence of the term
+func indexAll(content string, term string) []Span {
+	var hits []Span
+	if term == "" {
+		return hits
+	}
+	for off := 0; ; {
+		i := strings.Index(content[off:], term)
+		if i < 0 {
+			return hits
+		}
+		hits = append(hits, Span{off + i, off + i + len(term)})
+		off += i + len(term)
+	}
+}
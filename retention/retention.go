@@ -0,0 +1,119 @@
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+)
+
+// Policy of the saved bins: zero values disable a limit
+type Policy struct {
+	// Delete the bins fetched before this age
+	MaxAge time.Duration
+	// Age by severity, overriding MaxAge: 0 keeps the bins of the severity forever
+	SeverityMaxAge map[string]time.Duration
+	// Delete the oldest bins while the storage is bigger, in bytes
+	MaxSize int64
+	// Keep the bins triaged as true positive
+	KeepTruePositives bool
+}
+
+// Saved bin with the size of its content
+type Bin struct {
+	Meta *filesupport.PasteMeta
+	Size int64
+	// Why the bin is purged
+	Reason string
+}
+
+// ParseAge parses a duration accepting days and weeks, i.e. '90d', '2w', '36h'
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// Enabled is true when the policy has a limit
+func (p *Policy) Enabled() bool {
+	if p == nil {
+		return false
+	}
+	return p.MaxAge > 0 || p.MaxSize > 0 || len(p.SeverityMaxAge) > 0
+}
+
+// Protected is true for the bins never purged: under legal hold or triaged as true positive
+func (p *Policy) Protected(meta *filesupport.PasteMeta) bool {
+	return meta.LegalHold || (p.KeepTruePositives && meta.Triage == filesupport.TruePositive)
+}
+
+// Maximum age of a bin, 0 for no limit
+func (p *Policy) maxAge(meta *filesupport.PasteMeta) time.Duration {
+	if age, ok := p.SeverityMaxAge[meta.Severity]; ok {
+		return age
+	}
+	return p.MaxAge
+}
+
+// Purge returns the bins to delete, oldest first: the expired ones, then the oldest ones until
+// the storage fits in MaxSize. The bins without a fetch time never expire
+func (p *Policy) Purge(bins []Bin, now time.Time) []Bin {
+	if !p.Enabled() {
+		return nil
+	}
+	sorted := make([]Bin, len(bins))
+	copy(sorted, bins)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Meta.FetchedAt.Before(sorted[j].Meta.FetchedAt) })
+
+	var out []Bin
+	var kept []Bin
+	var total int64
+	for _, b := range sorted {
+		age := p.maxAge(b.Meta)
+		if !p.Protected(b.Meta) && age > 0 && !b.Meta.FetchedAt.IsZero() && now.Sub(b.Meta.FetchedAt) > age {
+			b.Reason = "older than " + formatAge(age)
+			out = append(out, b)
+			continue
+		}
+		kept = append(kept, b)
+		total += b.Size
+	}
+	if p.MaxSize <= 0 {
+		return out
+	}
+	for _, b := range kept {
+		if total <= p.MaxSize {
+			break
+		}
+		if p.Protected(b.Meta) {
+			continue
+		}
+		b.Reason = fmt.Sprintf("storage over %d bytes", p.MaxSize)
+		out = append(out, b)
+		total -= b.Size
+	}
+	return out
+}
+
+// Age in days when possible
+func formatAge(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
@@ -0,0 +1,77 @@
+package retention_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/retention"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in  string
+		out time.Duration
+		err bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"0", 0, false},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, test := range tests {
+		d, err := retention.ParseAge(test.in)
+		if (err != nil) != test.err || d != test.out {
+			t.Errorf("ParseAge(%q) = %s, %v", test.in, d, err)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	bin := func(id string, age time.Duration, severity string, size int64) retention.Bin {
+		return retention.Bin{Meta: &filesupport.PasteMeta{ID: id, Severity: severity, FetchedAt: now.Add(-age)}, Size: size}
+	}
+	bins := []retention.Bin{
+		bin("new", day, "low", 10),
+		bin("old", 40*day, "low", 10),
+		bin("critical", 40*day, "critical", 10),
+		bin("triaged", 40*day, "low", 10),
+		bin("hold", 50*day, "low", 10),
+		bin("mid", 20*day, "low", 10),
+		{Meta: &filesupport.PasteMeta{ID: "undated"}, Size: 10},
+	}
+	bins[3].Meta.Triage = filesupport.TruePositive
+	bins[4].Meta.LegalHold = true
+
+	tests := []struct {
+		name   string
+		policy retention.Policy
+		out    []string
+	}{
+		{"disabled", retention.Policy{}, nil},
+		{"age", retention.Policy{MaxAge: 30 * day, KeepTruePositives: true}, []string{"old", "critical"}},
+		{"triaged", retention.Policy{MaxAge: 30 * day}, []string{"old", "critical", "triaged"}},
+		{"severity", retention.Policy{MaxAge: 30 * day, KeepTruePositives: true,
+			SeverityMaxAge: map[string]time.Duration{"critical": 0, "low": 10 * day}}, []string{"old", "mid"}},
+		// Oldest first: the undated bin is the oldest
+		{"size", retention.Policy{MaxSize: 35, KeepTruePositives: true}, []string{"undated", "old", "critical", "mid"}},
+		{"age and size", retention.Policy{MaxAge: 30 * day, MaxSize: 35, KeepTruePositives: true}, []string{"old", "critical", "undated", "mid"}},
+	}
+	for _, test := range tests {
+		var ids []string
+		for _, b := range test.policy.Purge(bins, now) {
+			ids = append(ids, b.Meta.ID)
+			if b.Reason == "" {
+				t.Errorf("%s: %s without reason", test.name, b.Meta.ID)
+			}
+		}
+		if !reflect.DeepEqual(ids, test.out) {
+			t.Errorf("%s: purged %v, want %v", test.name, ids, test.out)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Folders of the samples of a rule: '<samples>/<rule name>/match' and '<samples>/<rule name>/nomatch'
+const (
+	MatchDir   = "match"
+	NoMatchDir = "nomatch"
+)
+
+// TestResult is the outcome of the samples of a rule
+type TestResult struct {
+	Rule    string
+	Samples int
+	// Samples with the wrong outcome, relative to the folder of the rule
+	Failures []string
+}
+
+// RunTests checks every rule against its labelled samples: the bins in the 'match' folder
+// must match the rule, the bins in the 'nomatch' folder must not
+func RunTests(m *Matcher, samples string) ([]TestResult, error) {
+	dirs, err := ioutil.ReadDir(samples)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*Rule{}
+	for _, r := range m.Rules {
+		byName[r.Name] = r
+	}
+	for _, d := range dirs {
+		if _, ok := byName[d.Name()]; d.IsDir() && !ok {
+			return nil, fmt.Errorf("samples for unknown rule %q", d.Name())
+		}
+	}
+
+	var results []TestResult
+	for _, r := range m.Rules {
+		result := TestResult{Rule: r.Name}
+		for _, label := range []string{MatchDir, NoMatchDir} {
+			dir := filepath.Join(samples, r.Name, label)
+			files, err := ioutil.ReadDir(dir)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			for _, f := range files {
+				if f.IsDir() {
+					continue
+				}
+				b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+				if err != nil {
+					return nil, err
+				}
+				result.Samples++
+				if got, _ := m.MatchRule(r, string(b)); got != (label == MatchDir) {
+					result.Failures = append(result.Failures, filepath.Join(label, f.Name()))
+				}
+			}
+		}
+		sort.Strings(result.Failures)
+		results = append(results, result)
+	}
+	return results, nil
+}
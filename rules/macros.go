@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Macros are named sub-expressions referenced as '$name' from the rules
+type Macros map[string]string
+
+var reMacroName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseMacro parses a macro definition: '$noise = php || sudo || Linux'
+func ParseMacro(def string) (string, string, error) {
+	i := strings.Index(def, "=")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid macro %q, expected '$name = expression'", def)
+	}
+	name := strings.TrimPrefix(strings.TrimSpace(def[:i]), "$")
+	expr := strings.TrimSpace(def[i+1:])
+	if !reMacroName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid macro name %q", name)
+	}
+	if expr == "" {
+		return "", "", fmt.Errorf("macro $%s: empty expression", name)
+	}
+	return name, expr, nil
+}
+
+// Expand replaces the references to the macros with their expressions, between parenthesis.
+// A reference starts a term: '$$' in 'pa$$word' or quoted strings are not references
+func (m Macros) Expand(expr string) (string, error) {
+	return m.expand(expr, nil)
+}
+
+func (m Macros) expand(expr string, stack []string) (string, error) {
+	var out strings.Builder
+	quoted := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if c != '$' || quoted || (i > 0 && !strings.ContainsRune(" \t\r\n(~", rune(expr[i-1]))) {
+			out.WriteByte(c)
+			continue
+		}
+		j := i + 1
+		for j < len(expr) && (expr[j] == '_' || expr[j] >= 'a' && expr[j] <= 'z' || expr[j] >= 'A' && expr[j] <= 'Z' || expr[j] >= '0' && expr[j] <= '9') {
+			j++
+		}
+		name := expr[i+1 : j]
+		if !reMacroName.MatchString(name) {
+			out.WriteByte(c)
+			continue
+		}
+		body, ok := m[name]
+		if !ok {
+			return "", fmt.Errorf("undefined macro $%s", name)
+		}
+		for k, s := range stack {
+			if s == name {
+				return "", fmt.Errorf("macro cycle: $%s -> $%s", strings.Join(stack[k:], " -> $"), name)
+			}
+		}
+		expanded, err := m.expand(body, append(stack, name))
+		if err != nil {
+			return "", err
+		}
+		out.WriteString("(" + expanded + ")")
+		i = j - 1
+	}
+	return out.String(), nil
+}
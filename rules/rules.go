@@ -0,0 +1,336 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/notdodo/pastego/decoder"
+	"github.com/notdodo/pastego/normalize"
+	"github.com/notdodo/pastego/pegmatch"
+)
+
+// Severities from the lowest to the highest
+var Severities = []string{"info", "low", "medium", "high", "critical"}
+
+// Expressions, from the command line, starting with this prefix are matched against the normalized content
+const NormPrefix = "norm:"
+
+// Rule is a named expression
+type Rule struct {
+	Name        string   `json:"name"`
+	Expr        string   `json:"expr"`
+	Severity    string   `json:"severity,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// Match against the normalized content of the bin
+	Normalize bool `json:"normalize,omitempty"`
+
+	// Expression with the macros expanded
+	compiled string
+}
+
+// Content of a rules file
+type file struct {
+	Macros Macros  `json:"macros"`
+	Rules  []*Rule `json:"rules"`
+}
+
+// Load the rules from a JSON file: {"macros": {"name": "..."}, "rules": [{"name": "...", "expr": "...", ...}]}.
+// The expressions can reference the macros of the file and 'macros', overriding them; 'macros' is not modified
+func Load(path string, macros Macros) ([]*Rule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	all := Macros{}
+	for name, expr := range f.Macros {
+		name = strings.TrimPrefix(name, "$")
+		if !reMacroName.MatchString(name) {
+			return nil, fmt.Errorf("%s: invalid macro name %q", path, name)
+		}
+		all[name] = expr
+	}
+	for name, expr := range macros {
+		all[name] = expr
+	}
+	if err := Validate(f.Rules, all); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return f.Rules, nil
+}
+
+// FromSearch creates anonymous rules from comma separated expressions:
+// the name of a rule is the first word of its expression
+func FromSearch(search string) []*Rule {
+	var rs []*Rule
+	for _, expr := range strings.Split(search, ",") {
+		r := &Rule{Expr: strings.TrimSpace(expr), Severity: "info"}
+		if strings.HasPrefix(r.Expr, NormPrefix) {
+			r.Expr = strings.TrimSpace(strings.TrimPrefix(r.Expr, NormPrefix))
+			r.Normalize = true
+		}
+		if r.Expr == "" {
+			continue
+		}
+		r.Name = strings.Split(r.Expr, " ")[0]
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// Validate checks the names, the severities and the syntax of the expressions, expanding the macros
+func Validate(rs []*Rule, macros Macros) error {
+	names := map[string]bool{}
+	for i, r := range rs {
+		if r.Name == "" {
+			return fmt.Errorf("rule #%d: missing name", i+1)
+		}
+		if r.Name != govalidator.SafeFileName(r.Name) || strings.Contains(r.Name, "__") {
+			return fmt.Errorf("rule %q: the name must be a valid file name without '__'", r.Name)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("rule %q: duplicated name", r.Name)
+		}
+		names[r.Name] = true
+		if r.Severity == "" {
+			r.Severity = "info"
+		}
+		if SeverityLevel(r.Severity) < 0 {
+			return fmt.Errorf("rule %q: unknown severity %q, available: %s", r.Name, r.Severity, strings.Join(Severities, ","))
+		}
+		if err := r.Expand(macros); err != nil {
+			return fmt.Errorf("rule %q: %s", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Expand the macros of the expression and check its syntax
+func (r *Rule) Expand(macros Macros) error {
+	expr, err := macros.Expand(r.Expr)
+	if err != nil {
+		return err
+	}
+	if err := Compile(expr); err != nil {
+		return err
+	}
+	r.compiled = expr
+	return nil
+}
+
+// Expression to evaluate: with the macros expanded if any
+func (r *Rule) expression() string {
+	if r.compiled != "" {
+		return r.compiled
+	}
+	return r.Expr
+}
+
+// Compile checks the syntax of an expression
+func Compile(expr string) error {
+	pegmatch.Lock.Lock()
+	defer pegmatch.Lock.Unlock()
+	content := pegmatch.PasteContentString
+	defer func() { pegmatch.PasteContentString = content }()
+	pegmatch.PasteContentString = ""
+	_, err := pegmatch.ParseReader("", bytes.NewBufferString(expr))
+	return err
+}
+
+// SeverityLevel returns the position of the severity in Severities, -1 if unknown
+func SeverityLevel(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Matcher checks the rules against the content of the bins
+type Matcher struct {
+	Rules           []*Rule
+	CaseInsensitive bool
+	Normalizer      *normalize.Normalizer
+	// Decode the blobs of the bins up to this depth, see decoder.Decode
+	DecodeDepth   int
+	DecodeMaxSize int
+}
+
+// Layers decodes the blobs of the content, the first layer is the content itself: decode a bin once
+// and check all the rules against its layers with MatchLayers, MatchAllLayers and ExplainLayers
+func (m *Matcher) Layers(content string) []decoder.Layer {
+	return decoder.Decode(content, m.DecodeDepth, m.DecodeMaxSize)
+}
+
+// Match returns the first rule matching the content, or the blobs decoded from it,
+// and the decoders of the matching layer. The rule is nil if none matches
+func (m *Matcher) Match(content string) (*Rule, string) {
+	return m.MatchLayers(m.Layers(content))
+}
+
+// MatchLayers is Match on the layers of a bin, see Layers
+func (m *Matcher) MatchLayers(layers []decoder.Layer) (*Rule, string) {
+	return m.match(m.Rules, layers)
+}
+
+// MatchRule checks a single rule against the content, see Match
+func (m *Matcher) MatchRule(r *Rule, content string) (bool, string) {
+	return m.MatchRuleLayers(r, m.Layers(content))
+}
+
+// MatchRuleLayers is MatchRule on the layers of a bin, see Layers
+func (m *Matcher) MatchRuleLayers(r *Rule, layers []decoder.Layer) (bool, string) {
+	rule, layer := m.match([]*Rule{r}, layers)
+	return rule != nil, layer
+}
+
+func (m *Matcher) match(rs []*Rule, layers []decoder.Layer) (*Rule, string) {
+	pegmatch.Lock.Lock()
+	defer pegmatch.Lock.Unlock()
+	pegmatch.CaseInsensitive = m.CaseInsensitive
+	for _, layer := range layers {
+		// Normalize and convert the content only once for all the rules
+		prepared := map[bool]string{}
+		for _, r := range rs {
+			norm := r.Normalize && m.Normalizer != nil
+			text, ok := prepared[norm]
+			if !ok {
+				text = layer.Text
+				if norm {
+					text = m.Normalizer.Normalize(text)
+				}
+				if m.CaseInsensitive {
+					text = strings.ToUpper(text)
+				}
+				prepared[norm] = text
+			}
+			pegmatch.PasteContentString = text
+			got, err := pegmatch.ParseReader("", bytes.NewBufferString(r.expression()))
+			if err == nil && got.(bool) {
+				return r, layer.Path
+			}
+		}
+	}
+	return nil, ""
+}
+
+// Hit of a rule found by MatchAll
+type Hit struct {
+	Rule *Rule
+	// Decoders of the matching layer
+	Layer string
+	// Text searched: the layer, normalized or converted to upper case, the spans refer to it
+	Text  string
+	Spans []pegmatch.Span
+}
+
+// MatchAll returns every rule matching the content, or the blobs decoded from it, with the spans of the
+// terms responsible for the match: the terms that are not negated
+func (m *Matcher) MatchAll(content string) []Hit {
+	return m.MatchAllLayers(m.Layers(content))
+}
+
+// MatchAllLayers is MatchAll on the layers of a bin, see Layers
+func (m *Matcher) MatchAllLayers(layers []decoder.Layer) []Hit {
+	var hits []Hit
+	for _, r := range m.Rules {
+		tree, layer, text, err := m.ExplainLayers(r, layers)
+		if err != nil || !tree.Value {
+			continue
+		}
+		hits = append(hits, Hit{Rule: r, Layer: layer, Text: text, Spans: positiveSpans(tree, nil)})
+	}
+	return hits
+}
+
+// Collect the spans of the true terms, skipping the negated ones
+func positiveSpans(n *pegmatch.Node, spans []pegmatch.Span) []pegmatch.Span {
+	if !n.Value || n.Op == "~" {
+		return spans
+	}
+	spans = append(spans, n.Hits...)
+	for _, c := range n.Children {
+		spans = positiveSpans(c, spans)
+	}
+	return spans
+}
+
+// Explain evaluates the rule and returns the expression tree of the first matching layer, or of the original
+// content if none matches, with the decoders of the layer and the text searched: the offsets of the hits refer to it
+func (m *Matcher) Explain(r *Rule, content string) (*pegmatch.Node, string, string, error) {
+	return m.ExplainLayers(r, m.Layers(content))
+}
+
+// ExplainLayers is Explain on the layers of a bin, see Layers
+func (m *Matcher) ExplainLayers(r *Rule, layers []decoder.Layer) (*pegmatch.Node, string, string, error) {
+	pegmatch.Lock.Lock()
+	defer pegmatch.Lock.Unlock()
+	pegmatch.CaseInsensitive = m.CaseInsensitive
+	var first *pegmatch.Node
+	var firstText string
+	for _, layer := range layers {
+		text := layer.Text
+		if r.Normalize && m.Normalizer != nil {
+			text = m.Normalizer.Normalize(text)
+		}
+		if m.CaseInsensitive {
+			text = strings.ToUpper(text)
+		}
+		pegmatch.PasteContentString = text
+		tree, err := pegmatch.Explain(r.expression())
+		if err != nil {
+			return nil, "", "", err
+		}
+		if tree.Value {
+			return tree, layer.Path, text, nil
+		}
+		if first == nil {
+			first, firstText = tree, text
+		}
+	}
+	return first, "", firstText, nil
+}
+
+// Diff describes the changes from the old rules to the new ones, empty if they are the same
+func Diff(oldRules []*Rule, newRules []*Rule) string {
+	before := map[string]*Rule{}
+	for _, r := range oldRules {
+		before[r.Name] = r
+	}
+	var added, changed, removed []string
+	for _, r := range newRules {
+		if o, ok := before[r.Name]; !ok {
+			added = append(added, r.Name)
+		} else if !reflect.DeepEqual(o, r) {
+			changed = append(changed, r.Name)
+		}
+		delete(before, r.Name)
+	}
+	for _, r := range oldRules {
+		if _, ok := before[r.Name]; ok {
+			removed = append(removed, r.Name)
+		}
+	}
+	var out []string
+	if len(added) > 0 {
+		out = append(out, "added: "+strings.Join(added, ", "))
+	}
+	if len(changed) > 0 {
+		out = append(out, "changed: "+strings.Join(changed, ", "))
+	}
+	if len(removed) > 0 {
+		out = append(out, "removed: "+strings.Join(removed, ", "))
+	}
+	return strings.Join(out, "; ")
+}
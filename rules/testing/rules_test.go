@@ -0,0 +1,228 @@
+package rules_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/normalize"
+	"github.com/notdodo/pastego/pegmatch"
+	"github.com/notdodo/pastego/rules"
+)
+
+func write(t *testing.T, path string, content string) {
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	invalid := map[string]string{
+		"syntax":    `{"rules": [{"name": "a", "expr": "pass &&"}]}`,
+		"name":      `{"rules": [{"expr": "pass"}]}`,
+		"duplicate": `{"rules": [{"name": "a", "expr": "pass"}, {"name": "a", "expr": "user"}]}`,
+		"severity":  `{"rules": [{"name": "a", "expr": "pass", "severity": "urgent"}]}`,
+		"filename":  `{"rules": [{"name": "a/b", "expr": "pass"}]}`,
+	}
+	for name, content := range invalid {
+		write(t, filepath.Join(dir, name), content)
+		if _, err := rules.Load(filepath.Join(dir, name), nil); err == nil {
+			t.Error("invalid rules accepted:", name)
+		}
+	}
+	write(t, filepath.Join(dir, "valid"), `{"rules": [{"name": "a", "expr": "pass", "tags": ["x"]}]}`)
+	rs, err := rules.Load(filepath.Join(dir, "valid"), nil)
+	if err != nil || len(rs) != 1 || rs[0].Severity != "info" || !reflect.DeepEqual(rs[0].Tags, []string{"x"}) {
+		t.Error("valid rules", rs, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+	write(t, path, `{"rules": [{"name": "a", "expr": "pass"}, {"name": "b", "expr": "user"}, {"name": "c", "expr": "key"}]}`)
+	before, err := rules.Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := rules.Diff(before, before); d != "" {
+		t.Error("same rules", d)
+	}
+	// Reload: 'a' unchanged, 'b' with a new severity, 'c' removed and 'd' added
+	write(t, path, `{"rules": [{"name": "a", "expr": "pass"}, {"name": "b", "expr": "user", "severity": "high"}, {"name": "d", "expr": "token"}]}`)
+	after, err := rules.Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := rules.Diff(before, after); d != "added: d; changed: b; removed: c" {
+		t.Error("diff", d)
+	}
+	// An invalid file is rejected: the current rules are kept
+	write(t, path, `{"rules": [{"name": "a", "expr": "pass &&"}]}`)
+	if _, err := rules.Load(path, nil); err == nil {
+		t.Error("invalid rules reloaded")
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	write(t, filepath.Join(dir, "quake", "match", "1.txt"), "quakelive")
+	write(t, filepath.Join(dir, "quake", "nomatch", "1.txt"), "earthquake")
+	write(t, filepath.Join(dir, "quake", "nomatch", "2.txt"), "nothing")
+	write(t, filepath.Join(dir, "password", "match", "1.txt"), "my password")
+	write(t, filepath.Join(dir, "password", "nomatch", "1.txt"), "my password is java")
+
+	m := &rules.Matcher{Rules: rules.FromSearch("quake && ~earthquake, password && ~php, secret")}
+	results, err := rules.RunTests(m, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rules.TestResult{
+		{Rule: "quake", Samples: 3},
+		{Rule: "password", Samples: 2, Failures: []string{filepath.Join("nomatch", "1.txt")}},
+		{Rule: "secret"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Error("results", results)
+	}
+
+	write(t, filepath.Join(dir, "unknown", "match", "1.txt"), "")
+	if _, err := rules.RunTests(m, dir); err == nil {
+		t.Error("samples of unknown rule accepted")
+	}
+}
+
+func TestMacros(t *testing.T) {
+	macros := rules.Macros{"noise": "php || sudo", "skip": "$noise || Linux", "a": "$b", "b": "~$a"}
+	expanded := map[string]string{
+		"pass && ~$noise":       "pass && ~(php || sudo)",
+		"pass && ~($skip)":      "pass && ~(((php || sudo) || Linux))",
+		"pa$$word && 'a $skip'": "pa$$word && 'a $skip'",
+	}
+	for expr, want := range expanded {
+		if got, err := macros.Expand(expr); err != nil || got != want {
+			t.Error(expr, got, err)
+		}
+	}
+	for _, expr := range []string{"pass && $undefined", "pass && $a"} {
+		if _, err := macros.Expand(expr); err == nil {
+			t.Error("invalid macro accepted:", expr)
+		}
+	}
+	if name, expr, err := rules.ParseMacro("$noise = php || sudo"); name != "noise" || expr != "php || sudo" || err != nil {
+		t.Error("parse macro", name, expr, err)
+	}
+
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	write(t, filepath.Join(dir, "rules"), `{"macros": {"$noise": "php || $extra"}, "rules": [{"name": "a", "expr": "pass && ~$noise"}]}`)
+	if _, err := rules.Load(filepath.Join(dir, "rules"), nil); err == nil {
+		t.Error("undefined macro accepted")
+	}
+	cli := rules.Macros{"extra": "sudo"}
+	rs, err := rules.Load(filepath.Join(dir, "rules"), cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cli) != 1 {
+		t.Error("macros of the file added to the command line ones", cli)
+	}
+	m := &rules.Matcher{Rules: rs}
+	if r, _ := m.Match("pass sudo"); r != nil {
+		t.Error("macro not expanded")
+	}
+	if r, _ := m.Match("pass"); r == nil {
+		t.Error("no match")
+	}
+
+	// Terms starting with '$' are quoted
+	write(t, filepath.Join(dir, "php"), `{"rules": [{"name": "php", "expr": "$password && user"}]}`)
+	if _, err := rules.Load(filepath.Join(dir, "php"), nil); err == nil {
+		t.Error("unquoted $password accepted")
+	}
+	write(t, filepath.Join(dir, "php"), `{"rules": [{"name": "php", "expr": "'$password' && user"}]}`)
+	if rs, err = rules.Load(filepath.Join(dir, "php"), nil); err != nil {
+		t.Fatal(err)
+	}
+	m = &rules.Matcher{Rules: rs}
+	if r, _ := m.Match("$user = 'admin'; $password = 'x';"); r == nil {
+		t.Error("quoted term not matched")
+	}
+	if r, _ := m.Match("user password"); r != nil {
+		t.Error("quoted term matched without '$'")
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	m := &rules.Matcher{Rules: rules.FromSearch("password && ~(php || java), sudo, ~1'pasword', root"), DecodeDepth: 1, DecodeMaxSize: 1024}
+	hits := m.MatchAll("my password: c3VkbyBzdWRvIHN1ZG8gcGFzc3dvcmQ=")
+	if len(hits) != 3 {
+		t.Fatal("hits", hits)
+	}
+	if hits[0].Rule.Name != "password" || hits[0].Layer != "" || !reflect.DeepEqual(hits[0].Spans, []pegmatch.Span{{Start: 3, End: 11}}) {
+		t.Error("password", hits[0])
+	}
+	if hits[1].Rule.Name != "sudo" || hits[1].Layer != "base64" || len(hits[1].Spans) != 3 {
+		t.Error("sudo", hits[1])
+	}
+	if hits[2].Rule.Name != "~1'pasword'" || hits[2].Text[hits[2].Spans[0].Start:hits[2].Spans[0].End] != "password" {
+		t.Error("fuzzy", hits[2])
+	}
+
+	// The layers decoded once give the same results
+	layers := m.Layers("my password: c3VkbyBzdWRvIHN1ZG8gcGFzc3dvcmQ=")
+	if len(layers) != 2 || !reflect.DeepEqual(m.MatchAllLayers(layers), hits) {
+		t.Error("layers", layers)
+	}
+	if rule, layer := m.MatchLayers(layers); rule != m.Rules[0] || layer != "" {
+		t.Error("match", rule, layer)
+	}
+	if ok, layer := m.MatchRuleLayers(m.Rules[1], layers); !ok || layer != "base64" {
+		t.Error("match rule", ok, layer)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	n, err := normalize.New(normalize.Steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := &rules.Rule{Name: "plain", Expr: "password && admin"}
+	normalized := &rules.Rule{Name: "normalized", Expr: "password && admin", Normalize: true}
+	m := &rules.Matcher{Rules: append([]*rules.Rule{plain, normalized}, rules.FromSearch("norm:password && admin")...), Normalizer: n}
+	// Zero-width space, Cyrillic 'а', an HTML entity and a fullwidth 'ａ'
+	content := "pass\u200bword for \u0430&#100;min: p\uff41ssword"
+
+	if ok, _ := m.MatchRule(plain, content); ok {
+		t.Error("plain rule matched the obfuscated text")
+	}
+	for _, r := range m.Rules[1:] {
+		if ok, _ := m.MatchRule(r, content); !ok {
+			t.Error("normalized rule", r.Name)
+		}
+	}
+	hits := m.MatchAll(content)
+	if len(hits) != 2 || hits[0].Rule != normalized || hits[0].Text != "password for admin: password" {
+		t.Error("hits", hits)
+	}
+
+	// The normalized text is only searched, the original one is saved
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	store := filesupport.NewLocal(dir)
+	meta := &filesupport.PasteMeta{PasteJSON: filesupport.PasteJSON{Key: "AAA"}, Match: hits[0].Rule.Name}
+	if saved, err := filesupport.Save(meta, content, store); err != nil || !saved {
+		t.Fatal("save", err)
+	}
+	if got, _ := filesupport.ReadBin(meta, store); got != content {
+		t.Error("saved text", got)
+	}
+}
@@ -0,0 +1,175 @@
+package suppress
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/notdodo/pastego/decoder"
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/rules"
+)
+
+// Format of the expiry date
+const DateFormat = "2006-01-02"
+
+// Entry silences the bins matching all its conditions
+type Entry struct {
+	Name string `json:"name"`
+	// Author of the bin
+	User string `json:"user,omitempty"`
+	// Regular expression on the title of the bin
+	Title string `json:"title,omitempty"`
+	// Syntax highlighting of the bin, i.e. 'php'
+	Syntax string `json:"syntax,omitempty"`
+	// SHA256 of the content of the bin
+	Hash string `json:"hash,omitempty"`
+	// Expression on the content of the bin
+	Expr string `json:"expr,omitempty"`
+	// The entry is ignored from this date: YYYY-MM-DD
+	Expires string `json:"expires,omitempty"`
+	Comment string `json:"comment,omitempty"`
+
+	title   *regexp.Regexp
+	expires time.Time
+	// Rule of the expression, with the macros expanded
+	rule *rules.Rule
+}
+
+// Stats of an entry: how many bins it suppressed
+type Stats struct {
+	Hits    int       `json:"hits"`
+	LastHit time.Time `json:"last_hit"`
+}
+
+// List of suppressions loaded from a file
+type List struct {
+	Entries []*Entry          `json:"suppressions"`
+	Stats   map[string]*Stats `json:"-"`
+}
+
+// Load the suppressions from a JSON file: {"suppressions": [{"name": "...", "user": "...", ...}]},
+// the expressions can reference the macros
+func Load(path string, macros rules.Macros) (*List, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &List{Stats: map[string]*Stats{}}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	names := map[string]bool{}
+	for i, e := range l.Entries {
+		if e.Name == "" {
+			return nil, fmt.Errorf("%s: suppression #%d: missing name", path, i+1)
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("%s: suppression %q: duplicated name", path, e.Name)
+		}
+		names[e.Name] = true
+		if e.User == "" && e.Title == "" && e.Syntax == "" && e.Hash == "" && e.Expr == "" {
+			return nil, fmt.Errorf("%s: suppression %q: no conditions", path, e.Name)
+		}
+		if e.Title != "" {
+			if e.title, err = regexp.Compile(e.Title); err != nil {
+				return nil, fmt.Errorf("%s: suppression %q: %s", path, e.Name, err)
+			}
+		}
+		if e.Expr != "" {
+			e.rule = &rules.Rule{Name: e.Name, Expr: e.Expr}
+			if err := e.rule.Expand(macros); err != nil {
+				return nil, fmt.Errorf("%s: suppression %q: %s", path, e.Name, err)
+			}
+		}
+		if e.Expires != "" {
+			if e.expires, err = time.Parse(DateFormat, e.Expires); err != nil {
+				return nil, fmt.Errorf("%s: suppression %q: %s", path, e.Name, err)
+			}
+		}
+		e.Hash = strings.ToLower(e.Hash)
+	}
+	return l, nil
+}
+
+// Expired is true when the expiry date is passed
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires.AddDate(0, 0, 1))
+}
+
+// Hash of the content of a bin as used by the suppressions
+func Hash(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
+
+// Match returns the first active entry suppressing the bin and updates its stats, nil if none.
+// 'layers' are the content of the bin decoded by rules.Matcher.Layers
+func (l *List) Match(link *filesupport.PasteJSON, layers []decoder.Layer, m *rules.Matcher) *Entry {
+	if l == nil || len(layers) == 0 {
+		return nil
+	}
+	content := layers[0].Text
+	now := time.Now()
+	var hash string
+	for _, e := range l.Entries {
+		if e.Expired(now) ||
+			(e.User != "" && !strings.EqualFold(e.User, link.User)) ||
+			(e.Syntax != "" && !strings.EqualFold(e.Syntax, link.Syntax)) ||
+			(e.title != nil && !e.title.MatchString(link.Title)) {
+			continue
+		}
+		if e.Hash != "" {
+			if hash == "" {
+				hash = Hash(content)
+			}
+			if hash != e.Hash {
+				continue
+			}
+		}
+		if e.rule != nil {
+			if ok, _ := m.MatchRuleLayers(e.rule, layers); !ok {
+				continue
+			}
+		}
+		s, ok := l.Stats[e.Name]
+		if !ok {
+			s = &Stats{}
+			l.Stats[e.Name] = s
+		}
+		s.Hits++
+		s.LastHit = now
+		return e
+	}
+	return nil
+}
+
+// LoadStats reads the stats saved by SaveStats, a missing file is not an error
+func (l *List) LoadStats(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &l.Stats)
+}
+
+// SaveStats writes the stats of the entries to a JSON file
+func (l *List) SaveStats(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.FileMode(0700)); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(l.Stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return filesupport.WriteFile(path, b, filesupport.FileMode)
+}
@@ -0,0 +1,146 @@
+package suppress_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/notdodo/pastego/filesupport"
+	"github.com/notdodo/pastego/rules"
+	"github.com/notdodo/pastego/suppress"
+)
+
+func write(t *testing.T, path string, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	invalid := map[string]string{
+		"json":       `{"suppressions": [`,
+		"name":       `{"suppressions": [{"user": "bot"}]}`,
+		"duplicate":  `{"suppressions": [{"name": "a", "user": "bot"}, {"name": "a", "syntax": "php"}]}`,
+		"conditions": `{"suppressions": [{"name": "a", "comment": "nothing"}]}`,
+		"title":      `{"suppressions": [{"name": "a", "title": "(untitled"}]}`,
+		"expr":       `{"suppressions": [{"name": "a", "expr": "pass &&"}]}`,
+		"expires":    `{"suppressions": [{"name": "a", "user": "bot", "expires": "31/12/2021"}]}`,
+	}
+	for name, content := range invalid {
+		write(t, filepath.Join(dir, name), content)
+		if _, err := suppress.Load(filepath.Join(dir, name), nil); err == nil {
+			t.Error("invalid suppressions accepted:", name)
+		}
+	}
+	if _, err := suppress.Load(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("missing file accepted")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	dump := "user:hunter22\n"
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(suppress.DateFormat)
+	write(t, filepath.Join(dir, "suppressions.json"), `{"suppressions": [
+		{"name": "bot", "user": "ConfigBot"},
+		{"name": "php", "syntax": "php", "title": "^Untitled$"},
+		{"name": "dump", "hash": "`+suppress.Hash(dump)+`"},
+		{"name": "tutorial", "expr": "'example.com' && tutorial"},
+		{"name": "expired", "syntax": "text", "expires": "2021-01-01"},
+		{"name": "today", "syntax": "go", "expires": "`+tomorrow+`"}
+	]}`)
+	l, err := suppress.Load(filepath.Join(dir, "suppressions.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &rules.Matcher{}
+	cases := []struct {
+		link    filesupport.PasteJSON
+		content string
+		want    string
+	}{
+		{filesupport.PasteJSON{User: "configbot"}, "password", "bot"},
+		{filesupport.PasteJSON{Syntax: "php", Title: "Untitled"}, "password", "php"},
+		{filesupport.PasteJSON{Syntax: "php", Title: "Untitled 2"}, "password", ""},
+		{filesupport.PasteJSON{}, dump, "dump"},
+		{filesupport.PasteJSON{}, dump + " ", ""},
+		{filesupport.PasteJSON{}, "a tutorial on example.com", "tutorial"},
+		{filesupport.PasteJSON{}, "a tutorial on example.org", ""},
+		{filesupport.PasteJSON{Syntax: "text"}, "password", ""},
+		{filesupport.PasteJSON{Syntax: "go"}, "password", "today"},
+	}
+	for _, c := range cases {
+		e := l.Match(&c.link, m.Layers(c.content), m)
+		if (e == nil && c.want != "") || (e != nil && e.Name != c.want) {
+			t.Error("match", c.link, c.content, e)
+		}
+	}
+	var none *suppress.List
+	if none.Match(&filesupport.PasteJSON{User: "configbot"}, m.Layers(""), m) != nil {
+		t.Error("nil list matched")
+	}
+
+	// Stats of the matching entries only, saved and loaded back
+	l.Match(&filesupport.PasteJSON{User: "configbot"}, m.Layers(""), m)
+	if s := l.Stats["bot"]; s == nil || s.Hits != 2 || time.Since(s.LastHit) > time.Minute {
+		t.Error("stats", s)
+	}
+	if _, ok := l.Stats["expired"]; ok {
+		t.Error("stats of an expired entry")
+	}
+	stats := filepath.Join(dir, "suppressions-stats.json")
+	if err := l.SaveStats(stats); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := suppress.Load(filepath.Join(dir, "suppressions.json"), nil)
+	if err := loaded.LoadStats(filepath.Join(dir, "missing.json")); err != nil || len(loaded.Stats) != 0 {
+		t.Error("missing stats", err)
+	}
+	if err := loaded.LoadStats(stats); err != nil || loaded.Stats["bot"] == nil || loaded.Stats["bot"].Hits != 2 || len(loaded.Stats) != 5 {
+		t.Error("loaded stats", loaded.Stats, err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	write(t, filepath.Join(dir, "suppressions.json"), `{"suppressions": [{"name": "a", "user": "bot", "expires": "2021-03-01"}, {"name": "b", "user": "bot"}]}`)
+	l, err := suppress.Load(filepath.Join(dir, "suppressions.json"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Active until the end of the expiry date
+	lastDay := time.Date(2021, 3, 1, 23, 59, 0, 0, time.UTC)
+	if l.Entries[0].Expired(lastDay) || !l.Entries[0].Expired(lastDay.Add(time.Minute)) {
+		t.Error("expiry date")
+	}
+	if l.Entries[1].Expired(lastDay.AddDate(10, 0, 0)) {
+		t.Error("entry without expiry expired")
+	}
+}
+
+func TestMacros(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "suppressions.json")
+	write(t, path, `{"suppressions": [{"name": "docs", "expr": "tutorial && $docs"}]}`)
+	if _, err := suppress.Load(path, nil); err == nil {
+		t.Error("undefined macro accepted")
+	}
+	l, err := suppress.Load(path, rules.Macros{"docs": "'example.com' || readme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &rules.Matcher{}
+	if e := l.Match(&filesupport.PasteJSON{}, m.Layers("a tutorial on example.com"), m); e == nil || e.Name != "docs" {
+		t.Error("macro not expanded", e)
+	}
+	if e := l.Match(&filesupport.PasteJSON{}, m.Layers("a tutorial on example.org"), m); e != nil {
+		t.Error("match", e)
+	}
+}
@@ -0,0 +1,118 @@
+package unpack_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/notdodo/pastego/unpack"
+)
+
+func zipOf(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func tarGzOf(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func walk(data []byte, limits unpack.Limits) map[string]string {
+	found := map[string]string{}
+	unpack.Walk("dump", data, limits, func(m unpack.Member) {
+		if m.Err != nil {
+			found[m.Path] = "error"
+		} else {
+			found[m.Path] = string(m.Data)
+		}
+	})
+	return found
+}
+
+func TestWalk(t *testing.T) {
+	limits := unpack.Limits{MaxSize: 1 << 20, MaxTotal: 1 << 22, MaxRatio: 100, MaxDepth: 3}
+	inner := tarGzOf(t, map[string][]byte{"dir/secret.txt": []byte("password")})
+	archive := zipOf(t, map[string][]byte{"a.txt": []byte("hello"), "inner.tar.gz": inner})
+
+	want := map[string]string{"dump!a.txt": "hello", "dump!inner.tar.gz!dir/secret.txt": "password"}
+	if got := walk(archive, limits); !reflect.DeepEqual(got, want) {
+		t.Error("members", got)
+	}
+	if got := walk([]byte("plain"), limits); !reflect.DeepEqual(got, map[string]string{"dump": "plain"}) {
+		t.Error("plain file", got)
+	}
+
+	limits.MaxDepth = 1
+	want = map[string]string{"dump!a.txt": "hello", "dump!inner.tar.gz": "error"}
+	if got := walk(archive, limits); !reflect.DeepEqual(got, want) {
+		t.Error("depth", got)
+	}
+
+	limits.MaxDepth = 3
+	bomb := zipOf(t, map[string][]byte{"zeros": []byte(strings.Repeat("0", 1<<20))})
+	if got := walk(bomb, limits); got["dump!zeros"] != "error" {
+		t.Error("ratio", got)
+	}
+	// The ratio of the members of a '.tar.gz' is checked on the compressed stream
+	tarBomb := tarGzOf(t, map[string][]byte{"zeros": []byte(strings.Repeat("0", 1<<20))})
+	if got := walk(tarBomb, limits); got["dump!zeros"] != "error" {
+		t.Error("tar.gz ratio", len(got["dump!zeros"]))
+	}
+	limits.MaxRatio, limits.MaxSize = 10000, 1000
+	if got := walk(bomb, limits); got["dump!zeros"] != "error" {
+		t.Error("size", got)
+	}
+	limits.MaxSize = 1 << 20
+	if got := walk(tarBomb, limits); got["dump!zeros"] == "error" {
+		t.Error("tar.gz under the ratio", len(got["dump!zeros"]))
+	}
+
+	// Only the members count against the total, not the archives holding them
+	limits = unpack.Limits{MaxSize: 1 << 20, MaxTotal: 100000, MaxRatio: 10000, MaxDepth: 3}
+	members := map[string][]byte{"a": bytes.Repeat([]byte("a"), 40000), "b": bytes.Repeat([]byte("b"), 40000)}
+	for name, archive := range map[string][]byte{"tar.gz": tarGzOf(t, members), "zip": zipOf(t, map[string][]byte{"inner.zip": zipOf(t, members)})} {
+		got := walk(archive, limits)
+		if len(got) != 2 {
+			t.Error("total", name, len(got))
+		}
+		for path, data := range got {
+			if data == "error" {
+				t.Error("total", name, path)
+			}
+		}
+	}
+	limits.MaxTotal = 60000
+	read := 0
+	for _, data := range walk(tarGzOf(t, members), limits) {
+		if data != "error" {
+			read++
+		}
+	}
+	if read != 1 {
+		t.Error("over the total", read)
+	}
+}
@@ -0,0 +1,250 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// Separator between the path of an archive and the path of its member: 'dump.zip!inner/file.txt'
+const Separator = "!"
+
+// Limits against zip bombs
+type Limits struct {
+	// Maximum uncompressed size of a member
+	MaxSize int64
+	// Maximum uncompressed size of all the members of an archive
+	MaxTotal int64
+	// Maximum ratio between the uncompressed and the compressed size of a member, and of the stream of a '.tar.gz'
+	MaxRatio float64
+	// Maximum nesting of archives, 1 reads the members of the archive but not the archives inside it
+	MaxDepth int
+}
+
+// Member of an archive, or the whole file if it is not an archive
+type Member struct {
+	Path string
+	Data []byte
+	// Why the member has been skipped: a limit or a corrupted archive
+	Err error
+}
+
+// Kind of archive of the content, empty if is not an archive
+func Kind(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return "zip"
+	case bytes.HasPrefix(data, []byte("\x1f\x8b")):
+		return "gzip"
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return "tar"
+	}
+	return ""
+}
+
+// walker keeps the amount of bytes extracted: only the members that are not archives count,
+// the nested archives are counted once, by their members
+type walker struct {
+	limits Limits
+	total  int64
+	fn     func(Member)
+}
+
+// Walk calls fn for every file inside the archive, and inside the archives it contains, within the limits.
+// A file that is not an archive is passed as it is
+func Walk(name string, data []byte, limits Limits, fn func(Member)) {
+	w := &walker{limits: limits, fn: fn}
+	w.walk(name, data, 0)
+}
+
+func (w *walker) walk(name string, data []byte, depth int) {
+	kind := Kind(data)
+	if kind == "" {
+		w.total += int64(len(data))
+		w.fn(Member{Path: name, Data: data})
+		return
+	}
+	if depth >= w.limits.MaxDepth {
+		w.fn(Member{Path: name, Err: fmt.Errorf("%s archive nested too deep", kind)})
+		return
+	}
+	var err error
+	switch kind {
+	case "zip":
+		err = w.zip(name, data, depth)
+	case "gzip":
+		err = w.gzip(name, data, depth)
+	case "tar":
+		err = w.tar(name, bytes.NewReader(data), depth)
+	}
+	if err != nil {
+		w.fn(Member{Path: name, Err: err})
+	}
+}
+
+// Read a member within the size limits, 'compressed' is its size before extraction
+func (w *walker) read(r io.Reader, compressed int64) ([]byte, error) {
+	max := w.limits.MaxSize
+	if left := w.limits.MaxTotal - w.total; left < max {
+		max = left
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		if max < w.limits.MaxSize {
+			return nil, fmt.Errorf("archive bigger than %d bytes", w.limits.MaxTotal)
+		}
+		return nil, fmt.Errorf("member bigger than %d bytes", w.limits.MaxSize)
+	}
+	if err := w.ratio(int64(len(data)), compressed); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Check the compression ratio, unknown when 'compressed' is 0
+func (w *walker) ratio(size int64, compressed int64) error {
+	if compressed > 0 && float64(size)/float64(compressed) > w.limits.MaxRatio {
+		return fmt.Errorf("compression ratio over %g", w.limits.MaxRatio)
+	}
+	return nil
+}
+
+// Compressed input of a gzip stream, counting the bytes consumed: it is an io.ByteReader,
+// so gzip does not read ahead
+type compressedReader struct {
+	r *bytes.Reader
+	n int64
+}
+
+func (c *compressedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *compressedReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Decompressed output of a gzip stream, counting the bytes extracted from the compressed ones of 'in'
+type extractedReader struct {
+	r  io.Reader
+	n  int64
+	in *compressedReader
+}
+
+func (e *extractedReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	e.n += int64(n)
+	return n, err
+}
+
+func (w *walker) zip(name string, data []byte, depth int) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		member := name + Separator + f.Name
+		rc, err := f.Open()
+		if err != nil {
+			w.fn(Member{Path: member, Err: err})
+			continue
+		}
+		b, err := w.read(rc, int64(f.CompressedSize64))
+		rc.Close()
+		if err != nil {
+			w.fn(Member{Path: member, Err: err})
+			if w.total >= w.limits.MaxTotal {
+				return nil
+			}
+			continue
+		}
+		w.walk(member, b, depth+1)
+	}
+	return nil
+}
+
+// A gzip holds a single file: a tar ('.tar.gz') is walked as a member of the same level,
+// reading its members from the decompressed stream
+func (w *walker) gzip(name string, data []byte, depth int) error {
+	in := &compressedReader{r: bytes.NewReader(data)}
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+	if head, _ := br.Peek(263); Kind(head) == "tar" {
+		return w.tar(name, &extractedReader{r: io.LimitReader(br, w.limits.MaxTotal-w.total), in: in}, depth)
+	}
+	b, err := w.read(br, int64(len(data)))
+	if err != nil {
+		return err
+	}
+	inner := zr.Name
+	if inner == "" {
+		inner = strings.TrimSuffix(path.Base(name), ".gz")
+	}
+	w.walk(name+Separator+inner, b, depth+1)
+	return nil
+}
+
+// Walk the members of a tar. For a '.tar.gz' 'r' is the extractedReader of the gzip: the compression ratio
+// is checked for every member and for the whole stream
+func (w *walker) tar(name string, r io.Reader, depth int) error {
+	gz, _ := r.(*extractedReader)
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// The members skipped by Next are extracted too
+		if gz != nil {
+			if err := w.ratio(gz.n, gz.in.n); err != nil {
+				return err
+			}
+		}
+		if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeRegA {
+			continue
+		}
+		member := name + Separator + h.Name
+		var start int64
+		if gz != nil {
+			start = gz.in.n
+		}
+		b, err := w.read(tr, 0)
+		if err == nil && gz != nil {
+			err = w.ratio(int64(len(b)), gz.in.n-start)
+		}
+		if err != nil {
+			w.fn(Member{Path: member, Err: err})
+			if w.total >= w.limits.MaxTotal {
+				return nil
+			}
+			continue
+		}
+		w.walk(member, b, depth+1)
+	}
+}
@@ -0,0 +1,85 @@
+package watchlist_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/notdodo/pastego/watchlist"
+)
+
+func TestDomains(t *testing.T) {
+	cases := []struct {
+		arg     string
+		content string
+		want    int
+	}{
+		// An address and its domain are one hit
+		{"corp.com", "root@vpn.corp.com", 1},
+		{"corp.com", "root@vpn.corp.com and admin@corp.com", 2},
+		{"corp.com", "root@vpn.corp.com on vpn.corp.com, see intranet.corp.com", 2},
+		{"*.corp.com", "login at vpn.corp.com", 1},
+		{".corp.com", "login at corp.com", 1},
+		{"corp.com", "login at notcorp.com", 0},
+		{"corp.com other.org", "corp.com and other.org", 2},
+	}
+	for _, c := range cases {
+		if got, err := watchlist.Domains(c.arg, c.content); err != nil || got != c.want {
+			t.Error("domains", c.arg, c.content, got, err)
+		}
+	}
+}
+
+func TestIPs(t *testing.T) {
+	cases := []struct {
+		arg     string
+		content string
+		want    int
+	}{
+		{"10.1.2.3", "from 10.1.2.3", 1},
+		{"10.1.2.3", "from 10.1.2.4", 0},
+		{"10.1.0.0/16", "from 10.1.2.3 and 10.1.9.9, not 10.2.0.1", 2},
+		{"2001:db8::/32", "from 2001:db8::1 and 2001:db9::1", 1},
+		{"2001:db8::1", "from 2001:db8::1", 1},
+	}
+	for _, c := range cases {
+		if got, err := watchlist.IPs(c.arg, c.content); err != nil || got != c.want {
+			t.Error("ips", c.arg, c.content, got, err)
+		}
+	}
+	if _, err := watchlist.IPs("10.1.0.0/33", "10.1.2.3"); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pastego")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "corp.txt")
+	content := "mail to admin@corp.com from 10.1.2.3"
+
+	ioutil.WriteFile(path, []byte("# our domains\ncorp.com\n"), 0644)
+	if got, err := watchlist.Domains("@"+path, content); err != nil || got != 1 {
+		t.Error("domains", got, err)
+	}
+	if got, _ := watchlist.IPs("@"+path, content); got != 0 {
+		t.Error("ips", got)
+	}
+
+	// Read again when modified
+	ioutil.WriteFile(path, []byte("other.org\n10.1.0.0/16\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got, _ := watchlist.Domains("@"+path, content); got != 0 {
+		t.Error("domains not reloaded", got)
+	}
+	if got, _ := watchlist.IPs("@"+path, content); got != 1 {
+		t.Error("ips not reloaded", got)
+	}
+
+	if _, err := watchlist.Domains("@"+filepath.Join(dir, "missing.txt"), content); err == nil {
+		t.Error("missing watchlist accepted")
+	}
+}
@@ -0,0 +1,167 @@
+package watchlist
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/notdodo/pastego/indicators"
+)
+
+// A watchlist file: one domain, IP or CIDR per line, '#' starts a comment
+type list struct {
+	modTime time.Time
+	domains []string
+	nets    []*net.IPNet
+}
+
+var (
+	mutex sync.Mutex
+	lists = map[string]*list{}
+
+	// Indicators of the last bin: every rule of the same bin reuses them
+	lastText  string
+	lastFound *indicators.Indicators
+)
+
+// Load the watchlist from the file, the file is read again when modified
+func load(path string) (*list, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := lists[path]; ok && l.modTime.Equal(info.ModTime()) {
+		return l, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &list{modTime: info.ModTime()}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if err := l.add(strings.TrimSpace(line)); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	lists[path] = l
+	return l, nil
+}
+
+// Add an entry to the list: CIDR and IPs are networks, anything else is a domain
+func (l *list) add(entry string) error {
+	if entry == "" {
+		return nil
+	}
+	if strings.Contains(entry, "/") {
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return err
+		}
+		l.nets = append(l.nets, n)
+	} else if ip := net.ParseIP(entry); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		l.nets = append(l.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	} else {
+		l.domains = append(l.domains, strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(entry), "*"), "."))
+	}
+	return nil
+}
+
+// The argument of the function is a file ('@corp.txt') or a space separated list of entries
+func parseArg(arg string) (*list, error) {
+	if strings.HasPrefix(arg, "@") {
+		return load(strings.TrimPrefix(arg, "@"))
+	}
+	l := &list{}
+	for _, entry := range strings.Fields(arg) {
+		if err := l.add(entry); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func extract(content string) *indicators.Indicators {
+	if lastFound == nil || content != lastText {
+		lastText, lastFound = content, indicators.Extract(content)
+	}
+	return lastFound
+}
+
+func (l *list) matchDomain(d string) bool {
+	d = strings.ToLower(d)
+	for _, w := range l.domains {
+		if d == w || strings.HasSuffix(d, "."+w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Domains counts the email addresses and the domains (or subdomains) of the bin in the watchlist:
+// the domain of an email address is not counted again
+func Domains(arg string, content string) (int, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	l, err := parseArg(arg)
+	if err != nil {
+		return 0, err
+	}
+	found := extract(content)
+	count := 0
+	counted := map[string]bool{}
+	for _, e := range found.Emails {
+		d := strings.ToLower(e[strings.LastIndex(e, "@")+1:])
+		if l.matchDomain(d) {
+			count++
+			counted[d] = true
+		}
+	}
+	for _, d := range found.Domains {
+		d = strings.ToLower(d)
+		if !counted[d] && l.matchDomain(d) {
+			count++
+			counted[d] = true
+		}
+	}
+	return count, nil
+}
+
+// IPs counts the IP addresses of the bin inside the networks of the watchlist
+func IPs(arg string, content string) (int, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	l, err := parseArg(arg)
+	if err != nil {
+		return 0, err
+	}
+	found := extract(content)
+	count := 0
+	for _, s := range append(append([]string{}, found.IPv4...), found.IPv6...) {
+		ip := net.ParseIP(s)
+		for _, n := range l.nets {
+			if n.Contains(ip) {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}